@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"monke/token"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 type Node interface {
@@ -23,6 +26,10 @@ type Expression interface {
 
 type Program struct {
     Statements []Statement
+
+    // Comments are only collected when the lexer emits them,
+    // see lexer.WithComments.
+    Comments []token.Token
 }
 
 func (p *Program) TokenLiteral() string {
@@ -45,6 +52,9 @@ func (p *Program) String() string {
 type LetStatement struct {
     Token token.Token
     Name *Identifier
+    // set instead of Name when destructuring, let [a, b] = pair
+    Pattern Pattern
+    Type Type // let x: int = 1, nil without annotation
     Value Expression
 }
 
@@ -52,28 +62,114 @@ func (ls *LetStatement) statementNode() {}
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal}
 
 func (ls *LetStatement) String() string {
+    var target Node = ls.Name
+    if ls.Pattern != nil {
+        target = ls.Pattern
+    }
+    annotation := ""
+    if ls.Type != nil {
+        annotation = ": " + ls.Type.String()
+    }
     return fmt.Sprintf(
-        "%s %s = %s;",
+        "%s %s%s = %s;",
         ls.TokenLiteral(),
-        ls.Name.String(),
+        target.String(),
+        annotation,
         ls.Value.String())
 }
 
 type ReturnStatement struct {
     Token token.Token
-    Value Expression
+    Value Expression // nil for a bare return
 }
 
 func (rs *ReturnStatement) statementNode() {}
 func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal}
 
 func (rs *ReturnStatement) String() string {
+    if rs.Value == nil {
+        return rs.TokenLiteral() + ";"
+    }
     return fmt.Sprintf(
         "%s %s;",
         rs.TokenLiteral(),
         rs.Value.String())
 }
 
+type WhileStatement struct {
+    Token token.Token // while
+    Condition Expression
+    Body *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode() {}
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WhileStatement) String() string {
+    return "while " + parenthesize(ws.Condition) + " " + ws.Body.String()
+}
+
+// ForStatement runs Body once for every element of Iterable,
+// bound to Variable: for (x in xs) { ... }
+type ForStatement struct {
+    Token token.Token // for
+    Variable *Identifier
+    Iterable Expression
+    Body *BlockStatement
+}
+
+func (fs *ForStatement) statementNode() {}
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStatement) String() string {
+    return fmt.Sprintf(
+        "for (%s in %s) %s",
+        fs.Variable.String(),
+        fs.Iterable.String(),
+        fs.Body.String())
+}
+
+type BreakStatement struct {
+    Token token.Token // break
+}
+
+func (bs *BreakStatement) statementNode() {}
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BreakStatement) String() string { return bs.TokenLiteral() + ";" }
+
+type ContinueStatement struct {
+    Token token.Token // continue
+}
+
+func (cs *ContinueStatement) statementNode() {}
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ContinueStatement) String() string { return cs.TokenLiteral() + ";" }
+
+// ImportStatement is import "path/to/lib.monke" as lib, binding the
+// exports of the module at Path to Alias.
+type ImportStatement struct {
+    Token token.Token // import
+    Path *StringLiteral
+    Alias *Identifier
+}
+
+func (is *ImportStatement) statementNode() {}
+func (is *ImportStatement) TokenLiteral() string { return is.Token.Literal }
+func (is *ImportStatement) String() string {
+    return fmt.Sprintf("import %s as %s;", is.Path.String(), is.Alias.String())
+}
+
+// ExportStatement makes the names bound by a top level let visible to
+// modules importing this one.
+type ExportStatement struct {
+    Token token.Token // export
+    Statement *LetStatement
+}
+
+func (es *ExportStatement) statementNode() {}
+func (es *ExportStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExportStatement) String() string {
+    return "export " + es.Statement.String()
+}
+
 type ExpressionStatement struct {
     Token token.Token // first token
     Expression Expression
@@ -86,6 +182,31 @@ func (es *ExpressionStatement) String() string {
 }
 
 
+type BlockStatement struct {
+    Token token.Token // {
+    Statements []Statement
+}
+
+func (bs *BlockStatement) statementNode() {}
+func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal}
+func (bs *BlockStatement) String() string {
+    if len(bs.Statements) == 0 {
+        return "{}"
+    }
+
+    var out bytes.Buffer
+
+    out.WriteString("{ ")
+    for i, s := range bs.Statements {
+        if i > 0 {
+            out.WriteString(" ")
+        }
+        out.WriteString(s.String())
+    }
+    out.WriteString(" }")
+    return out.String()
+}
+
 type Identifier struct {
     Token token.Token
     Value string
@@ -105,6 +226,61 @@ func (i *Integer) expressionNode() {}
 func (i *Integer) TokenLiteral() string {return i.Token.Literal}
 func (i *Integer) String() string { return i.Token.Literal }
 
+type Boolean struct {
+    Token token.Token
+    Value bool
+}
+
+func (b *Boolean) expressionNode() {}
+func (b *Boolean) TokenLiteral() string {return b.Token.Literal}
+func (b *Boolean) String() string { return b.Token.Literal }
+
+type Float struct {
+    Token token.Token
+    Value float64
+}
+
+func (f *Float) expressionNode() {}
+func (f *Float) TokenLiteral() string {return f.Token.Literal}
+func (f *Float) String() string { return f.Token.Literal }
+
+type StringLiteral struct {
+    Token token.Token
+    Value string
+}
+
+func (sl *StringLiteral) expressionNode() {}
+func (sl *StringLiteral) TokenLiteral() string {return sl.Token.Literal}
+func (sl *StringLiteral) String() string { return quote(sl.Value) }
+
+// quote writes s back as a monke string literal, escaping quotes,
+// backslashes and non printable characters.
+func quote(s string) string {
+    var out bytes.Buffer
+
+    out.WriteByte('"')
+    for _, r := range s {
+        switch r {
+        case '"':
+            out.WriteString(`\"`)
+        case '\\':
+            out.WriteString(`\\`)
+        case '\n':
+            out.WriteString(`\n`)
+        case '\t':
+            out.WriteString(`\t`)
+        default:
+            if unicode.IsPrint(r) {
+                out.WriteRune(r)
+            } else {
+                fmt.Fprintf(&out, `\u{%x}`, r)
+            }
+        }
+    }
+    out.WriteByte('"')
+    return out.String()
+}
+
 type Operator struct {
     Token token.Token
 }
@@ -123,9 +299,14 @@ type PrefixExpression struct {
 func (pe *PrefixExpression) expressionNode() {}
 func (pe *PrefixExpression) TokenLiteral() string {return pe.Token.Literal}
 func (pe *PrefixExpression) String() string {
+    operator := pe.Operator
+    // word operators like "not" need a space before their operand
+    if last, _ := utf8.DecodeLastRuneInString(operator); token.IsIdentPart(last) {
+        operator += " "
+    }
     return fmt.Sprintf(
         "(%s%s)",
-        pe.Operator,
+        operator,
         pe.Right.String())
 }
 
@@ -146,3 +327,197 @@ func (ie *InfixExpression) String() string {
         ie .Operator,
         ie .Right.String())
 }
+
+// AssignExpression is x = v, xs[i] = v or a compound assignment
+// like x += v.
+type AssignExpression struct {
+    Token token.Token // the operator
+    Target Expression // *Identifier or *IndexExpression
+    Operator string
+    Value Expression
+}
+
+func (ae *AssignExpression) expressionNode() {}
+func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignExpression) String() string {
+    return fmt.Sprintf(
+        "(%s %s %s)",
+        ae.Target.String(),
+        ae.Operator,
+        ae.Value.String())
+}
+
+type IfExpression struct {
+    Token token.Token // if
+    Condition Expression
+    Consequence *BlockStatement
+    // *BlockStatement, *IfExpression for an else if, or nil without else
+    Alternative Node
+}
+
+func (ie *IfExpression) expressionNode() {}
+func (ie *IfExpression) TokenLiteral() string {return ie.Token.Literal}
+func (ie *IfExpression) String() string {
+    var out bytes.Buffer
+
+    out.WriteString("if ")
+    out.WriteString(parenthesize(ie.Condition))
+    out.WriteString(" ")
+    out.WriteString(ie.Consequence.String())
+    if ie.Alternative != nil {
+        out.WriteString(" else ")
+        out.WriteString(ie.Alternative.String())
+    }
+    return out.String()
+}
+
+// parenthesize wraps e in parentheses unless its String already does
+func parenthesize(e Expression) string {
+    switch e.(type) {
+    case *PrefixExpression, *InfixExpression, *AssignExpression:
+        return e.String()
+    }
+    return "(" + e.String() + ")"
+}
+
+type FunctionLiteral struct {
+    Token token.Token // fn
+    Parameters []*Identifier
+    // one per parameter, nil where it is not annotated
+    ParameterTypes []Type
+    ReturnType Type // fn() -> int, nil without annotation
+    Body *BlockStatement
+}
+
+func (fl *FunctionLiteral) expressionNode() {}
+func (fl *FunctionLiteral) TokenLiteral() string {return fl.Token.Literal}
+func (fl *FunctionLiteral) String() string {
+    params := []string{}
+    for i, p := range fl.Parameters {
+        if i < len(fl.ParameterTypes) && fl.ParameterTypes[i] != nil {
+            params = append(params, p.String() + ": " + fl.ParameterTypes[i].String())
+        } else {
+            params = append(params, p.String())
+        }
+    }
+    returns := ""
+    if fl.ReturnType != nil {
+        returns = " -> " + fl.ReturnType.String()
+    }
+    return fmt.Sprintf(
+        "%s(%s)%s %s",
+        fl.TokenLiteral(),
+        strings.Join(params, ", "),
+        returns,
+        fl.Body.String())
+}
+
+type CallExpression struct {
+    Token token.Token // (
+    Function Expression // identifier, function literal or another call
+    Arguments []Expression
+}
+
+func (ce *CallExpression) expressionNode() {}
+func (ce *CallExpression) TokenLiteral() string {return ce.Token.Literal}
+func (ce *CallExpression) String() string {
+    args := []string{}
+    for _, a := range ce.Arguments {
+        args = append(args, a.String())
+    }
+    return fmt.Sprintf(
+        "%s(%s)",
+        ce.Function.String(),
+        strings.Join(args, ", "))
+}
+
+type ArrayLiteral struct {
+    Token token.Token // [
+    Elements []Expression
+}
+
+func (al *ArrayLiteral) expressionNode() {}
+func (al *ArrayLiteral) TokenLiteral() string {return al.Token.Literal}
+func (al *ArrayLiteral) String() string {
+    elements := []string{}
+    for _, e := range al.Elements {
+        elements = append(elements, e.String())
+    }
+    return "[" + strings.Join(elements, ", ") + "]"
+}
+
+type IndexExpression struct {
+    Token token.Token // [
+    Left Expression
+    Index Expression
+}
+
+func (ie *IndexExpression) expressionNode() {}
+func (ie *IndexExpression) TokenLiteral() string {return ie.Token.Literal}
+func (ie *IndexExpression) String() string {
+    return fmt.Sprintf(
+        "(%s[%s])",
+        ie.Left.String(),
+        ie.Index.String())
+}
+
+type HashPair struct {
+    Key Expression
+    Value Expression
+}
+
+// HashLiteral keeps its pairs in source order, so that it prints back
+// the way it was written.
+type HashLiteral struct {
+    Token token.Token // {
+    Pairs []HashPair
+}
+
+func (hl *HashLiteral) expressionNode() {}
+func (hl *HashLiteral) TokenLiteral() string {return hl.Token.Literal}
+func (hl *HashLiteral) String() string {
+    pairs := []string{}
+    for _, pair := range hl.Pairs {
+        pairs = append(pairs, pair.Key.String() + ": " + pair.Value.String())
+    }
+    return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// StartOf returns the first token of an expression, which for operators
+// and calls is not the one the node keeps. Parentheses are not part of
+// the tree, so (a + b) starts at a.
+func StartOf(e Expression) token.Token {
+    switch e := e.(type) {
+    case *InfixExpression:
+        return StartOf(e.Left)
+    case *AssignExpression:
+        return StartOf(e.Target)
+    case *CallExpression:
+        return StartOf(e.Function)
+    case *IndexExpression:
+        return StartOf(e.Left)
+    case *Identifier:
+        return e.Token
+    case *Integer:
+        return e.Token
+    case *Float:
+        return e.Token
+    case *StringLiteral:
+        return e.Token
+    case *Boolean:
+        return e.Token
+    case *PrefixExpression:
+        return e.Token
+    case *IfExpression:
+        return e.Token
+    case *FunctionLiteral:
+        return e.Token
+    case *ArrayLiteral:
+        return e.Token
+    case *HashLiteral:
+        return e.Token
+    case *MatchExpression:
+        return e.Token
+    }
+    return token.Token{}
+}
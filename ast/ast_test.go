@@ -29,3 +29,29 @@ func TestString(t *testing.T) {
         t.Errorf("expected: '%s', got '%s'", expected, actual)
     }
 }
+
+func TestStringLiteralString(t *testing.T) {
+    tests := []struct {
+        value    string
+        expected string
+    }{
+        {"hello", `"hello"`},
+        {"", `""`},
+        {`say "hi"`, `"say \"hi\""`},
+        {`back\slash`, `"back\\slash"`},
+        {"a\nb\tc", `"a\nb\tc"`},
+        {"héllo 😀", `"héllo 😀"`},
+        {"bell\a", `"bell\u{7}"`},
+    }
+
+    for _, test := range tests {
+        s := &StringLiteral{
+            Token: token.Token{Type: token.STRING, Literal: test.value},
+            Value: test.value,
+        }
+        actual := s.String()
+        if actual != test.expected {
+            t.Errorf("expected: '%s', got '%s'", test.expected, actual)
+        }
+    }
+}
@@ -0,0 +1,158 @@
+package ast
+
+import (
+	"bytes"
+	"monke/token"
+	"strings"
+)
+
+// Pattern is matched against a value, binding names where it succeeds.
+type Pattern interface {
+    Node
+    patternNode()
+}
+
+// WildcardPattern is _, it matches anything and binds nothing.
+type WildcardPattern struct {
+    Token token.Token
+}
+
+func (wp *WildcardPattern) patternNode() {}
+func (wp *WildcardPattern) TokenLiteral() string { return wp.Token.Literal }
+func (wp *WildcardPattern) String() string { return "_" }
+
+// BindingPattern matches anything and binds it to Name.
+type BindingPattern struct {
+    Name *Identifier
+}
+
+func (bp *BindingPattern) patternNode() {}
+func (bp *BindingPattern) TokenLiteral() string { return bp.Name.TokenLiteral() }
+func (bp *BindingPattern) String() string { return bp.Name.String() }
+
+// LiteralPattern matches values equal to a number, string or boolean
+// literal. Negative numbers are a PrefixExpression.
+type LiteralPattern struct {
+    Value Expression
+}
+
+func (lp *LiteralPattern) patternNode() {}
+func (lp *LiteralPattern) TokenLiteral() string { return lp.Value.TokenLiteral() }
+func (lp *LiteralPattern) String() string { return lp.Value.String() }
+
+// ArrayPattern matches arrays with one element per pattern, or at least
+// that many if there is a Rest name to bind the remaining ones to.
+type ArrayPattern struct {
+    Token token.Token // [
+    Elements []Pattern
+    Rest *Identifier // [a, ...rest], nil without
+}
+
+func (ap *ArrayPattern) patternNode() {}
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+func (ap *ArrayPattern) String() string {
+    elements := []string{}
+    for _, e := range ap.Elements {
+        elements = append(elements, e.String())
+    }
+    if ap.Rest != nil {
+        elements = append(elements, "..." + ap.Rest.String())
+    }
+    return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// HashPatternPair matches the value under Key. A name as the key stands
+// for the string of that name, {name: n} reads the "name" entry.
+type HashPatternPair struct {
+    Key Expression // *Identifier or a literal
+    Value Pattern
+}
+
+func (hp HashPatternPair) String() string {
+    // {name} is short for {name: name}
+    if key, ok := hp.Key.(*Identifier); ok {
+        if binding, ok := hp.Value.(*BindingPattern); ok && binding.Name.Value == key.Value {
+            return key.Value
+        }
+    }
+    return hp.Key.String() + ": " + hp.Value.String()
+}
+
+// HashPattern matches hashes that have all of its keys, others are ignored.
+type HashPattern struct {
+    Token token.Token // {
+    Pairs []HashPatternPair
+}
+
+func (hp *HashPattern) patternNode() {}
+func (hp *HashPattern) TokenLiteral() string { return hp.Token.Literal }
+func (hp *HashPattern) String() string {
+    pairs := []string{}
+    for _, pair := range hp.Pairs {
+        pairs = append(pairs, pair.String())
+    }
+    return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// BoundNames returns the names a pattern binds, in source order with an
+// array's rest name after its elements.
+func BoundNames(pattern Pattern) []*Identifier {
+    names := []*Identifier{}
+    switch pattern := pattern.(type) {
+    case *BindingPattern:
+        names = append(names, pattern.Name)
+    case *ArrayPattern:
+        for _, element := range pattern.Elements {
+            names = append(names, BoundNames(element)...)
+        }
+        if pattern.Rest != nil {
+            names = append(names, pattern.Rest)
+        }
+    case *HashPattern:
+        for _, pair := range pattern.Pairs {
+            names = append(names, BoundNames(pair.Value)...)
+        }
+    }
+    return names
+}
+
+// MatchArm is pattern => body, or pattern if guard => body.
+type MatchArm struct {
+    Pattern Pattern
+    Guard Expression // nil without a guard
+    Body Expression
+}
+
+func (ma *MatchArm) String() string {
+    var out bytes.Buffer
+
+    out.WriteString(ma.Pattern.String())
+    if ma.Guard != nil {
+        out.WriteString(" if ")
+        out.WriteString(ma.Guard.String())
+    }
+    out.WriteString(" => ")
+    out.WriteString(ma.Body.String())
+    return out.String()
+}
+
+// MatchExpression evaluates to the body of the first arm whose pattern
+// matches Subject and whose guard holds.
+type MatchExpression struct {
+    Token token.Token // match
+    Subject Expression
+    Arms []*MatchArm
+}
+
+func (me *MatchExpression) expressionNode() {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) String() string {
+    arms := []string{}
+    for _, arm := range me.Arms {
+        arms = append(arms, arm.String())
+    }
+    if len(arms) == 0 {
+        return "match " + parenthesize(me.Subject) + " {}"
+    }
+    return "match " + parenthesize(me.Subject) + " { " + strings.Join(arms, ", ") + " }"
+}
@@ -0,0 +1,77 @@
+package ast
+
+import (
+	"monke/token"
+	"strings"
+)
+
+// Type is a type annotation, like the int in let x: int = 1.
+type Type interface {
+    Node
+    typeNode()
+}
+
+// NamedType is one of int, float, bool, string or any.
+type NamedType struct {
+    Token token.Token
+    Name string
+}
+
+func (nt *NamedType) typeNode() {}
+func (nt *NamedType) TokenLiteral() string { return nt.Token.Literal }
+func (nt *NamedType) String() string { return nt.Name }
+
+// ArrayType is array, or array[int] to give the element type.
+type ArrayType struct {
+    Token token.Token // array
+    Element Type // nil for any
+}
+
+func (at *ArrayType) typeNode() {}
+func (at *ArrayType) TokenLiteral() string { return at.Token.Literal }
+func (at *ArrayType) String() string {
+    if at.Element == nil {
+        return "array"
+    }
+    return "array[" + at.Element.String() + "]"
+}
+
+// HashType is hash, or hash[string, int] to give key and value types.
+type HashType struct {
+    Token token.Token // hash
+    Key Type   // nil for any
+    Value Type // nil for any
+}
+
+func (ht *HashType) typeNode() {}
+func (ht *HashType) TokenLiteral() string { return ht.Token.Literal }
+func (ht *HashType) String() string {
+    if ht.Key == nil {
+        return "hash"
+    }
+    return "hash[" + ht.Key.String() + ", " + ht.Value.String() + "]"
+}
+
+// FunctionType is fn for any function, or fn(int, int) -> int.
+type FunctionType struct {
+    Token token.Token // fn
+    Parameters []Type // nil for any parameters
+    Return Type       // nil for any
+}
+
+func (ft *FunctionType) typeNode() {}
+func (ft *FunctionType) TokenLiteral() string { return ft.Token.Literal }
+func (ft *FunctionType) String() string {
+    if ft.Parameters == nil && ft.Return == nil {
+        return "fn"
+    }
+    params := []string{}
+    for _, p := range ft.Parameters {
+        params = append(params, p.String())
+    }
+    out := "fn(" + strings.Join(params, ", ") + ")"
+    if ft.Return != nil {
+        out += " -> " + ft.Return.String()
+    }
+    return out
+}
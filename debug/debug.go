@@ -1,12 +1,64 @@
 package debug
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
 
-func Trace(s string) string {
-    fmt.Println("START ", s) 
+// Tracer logs nested START/END pairs, indenting by how deep they nest:
+//
+//     defer t.Un(t.Trace("parseExpression"))
+//
+// A nil Tracer logs nothing.
+type Tracer struct {
+    w io.Writer
+    depth int
+    Indent string
+}
+
+func NewTracer(w io.Writer) *Tracer {
+    return &Tracer{w: w, Indent: "  "}
+}
+
+func (t *Tracer) Trace(s string) string {
+    t.Log("START " + s)
+    if t != nil {
+        t.depth += 1
+    }
     return s
 }
 
+func (t *Tracer) Un(s string) {
+    if t == nil {
+        return
+    }
+    t.depth -= 1
+    t.Log("END " + s)
+}
+
+// Log writes s at the current depth.
+func (t *Tracer) Log(s string) {
+    if t == nil {
+        return
+    }
+    fmt.Fprintf(t.w, "%s%s\n", strings.Repeat(t.Indent, t.depth), s)
+}
+
+func (t *Tracer) Depth() int {
+    if t == nil {
+        return 0
+    }
+    return t.depth
+}
+
+var stdout = NewTracer(os.Stdout)
+
+func Trace(s string) string {
+    return stdout.Trace(s)
+}
+
 func Un(s string) { 
-    fmt.Println("END ", s) 
+    stdout.Un(s)
 }
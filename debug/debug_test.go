@@ -0,0 +1,38 @@
+package debug
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTracerIndents(t *testing.T) {
+    var out bytes.Buffer
+    tracer := NewTracer(&out)
+
+    func() {
+        defer tracer.Un(tracer.Trace("outer"))
+        tracer.Log("inside outer")
+        func() {
+            defer tracer.Un(tracer.Trace("inner"))
+        }()
+    }()
+
+    expected := `START outer
+  inside outer
+  START inner
+  END inner
+END outer
+`
+    if out.String() != expected {
+        t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+    }
+    if tracer.Depth() != 0 {
+        t.Errorf("expected depth 0 after all ends, got %d", tracer.Depth())
+    }
+}
+
+func TestNilTracer(t *testing.T) {
+    var tracer *Tracer
+    defer tracer.Un(tracer.Trace("nothing"))
+    tracer.Log("nothing")
+}
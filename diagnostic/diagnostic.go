@@ -0,0 +1,200 @@
+package diagnostic
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"monke/parser"
+	"monke/token"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type Severity string
+
+const (
+    Error   Severity = "error"
+    Warning Severity = "warning"
+)
+
+// Diagnostic is a message about a span of source text, rendered like
+//
+//     error[unexpected-token]: expected next token '=', got 'INT'
+//      --> script.monke:1:7
+//       |
+//     1 | let x 5;
+//       |       ^
+//       = help: ...
+type Diagnostic struct {
+    Severity Severity
+    Code string
+    Message string
+    Pos token.Position
+    End token.Position // end of the span, the zero Position marks a single character
+    Notes []string
+    Help string
+}
+
+// FromParseError turns a parser error into an error diagnostic that spans
+// the offending token.
+func FromParseError(e *parser.Error) Diagnostic {
+    d := Diagnostic{
+        Severity: Error,
+        Code: string(e.Code),
+        Message: e.Msg,
+        Pos: e.Pos,
+    }
+    if e.Found.Pos == e.Pos {
+        d.End = e.Found.End
+    }
+    return d
+}
+
+// Renderer writes diagnostics with a snippet of the source they are about.
+// Color enables ANSI escapes, meant for terminals only.
+type Renderer struct {
+    Color bool
+}
+
+const tabWidth = 4
+
+const (
+    bold   = "1"
+    red    = "1;31"
+    yellow = "1;33"
+    blue   = "1;34"
+)
+
+func (r Renderer) paint(s string, style string) string {
+    if !r.Color {
+        return s
+    }
+    return "\x1b[" + style + "m" + s + "\x1b[0m"
+}
+
+func (r Renderer) severityStyle(s Severity) string {
+    if s == Warning {
+        return yellow
+    }
+    return red
+}
+
+// Render writes d, quoting the line of source it points at.
+func (r Renderer) Render(w io.Writer, source string, d Diagnostic) error {
+    var out bytes.Buffer
+
+    style := r.severityStyle(d.Severity)
+    header := string(d.Severity)
+    if d.Code != "" {
+        header += "[" + d.Code + "]"
+    }
+    out.WriteString(r.paint(header, style))
+    out.WriteString(r.paint(": " + d.Message, bold))
+    out.WriteString("\n")
+
+    lineNumber := strconv.Itoa(d.Pos.Line)
+    pad := strings.Repeat(" ", len(lineNumber))
+    gutter := r.paint(pad + " |", blue)
+
+    out.WriteString(pad + r.paint("--> ", blue) + d.Pos.String() + "\n")
+
+    if d.Pos.IsValid() {
+        line := sourceLine(source, d.Pos.Line)
+        start, width := caret(line, d.Pos, d.End)
+
+        out.WriteString(gutter + "\n")
+        out.WriteString(r.paint(lineNumber + " |", blue) + " " + expandTabs(line) + "\n")
+        out.WriteString(gutter + " " + strings.Repeat(" ", start))
+        out.WriteString(r.paint(strings.Repeat("^", width), style) + "\n")
+    }
+
+    for _, note := range d.Notes {
+        out.WriteString(pad + r.paint(" = ", blue) + r.paint("note", bold) + ": " + note + "\n")
+    }
+    if d.Help != "" {
+        out.WriteString(pad + r.paint(" = ", blue) + r.paint("help", bold) + ": " + d.Help + "\n")
+    }
+
+    _, err := w.Write(out.Bytes())
+    return err
+}
+
+// RenderErrors writes every parser error in position order, followed by
+// a summary line.
+func (r Renderer) RenderErrors(w io.Writer, source string, errors parser.ErrorList) error {
+    errors.Sort()
+    for _, e := range errors {
+        if err := r.Render(w, source, FromParseError(e)); err != nil {
+            return err
+        }
+        fmt.Fprintln(w)
+    }
+
+    summary := fmt.Sprintf("could not parse due to %d previous errors", len(errors))
+    if len(errors) == 1 {
+        summary = "could not parse due to previous error"
+    }
+    _, err := fmt.Fprintf(w, "%s%s\n", r.paint("error", red), r.paint(": " + summary, bold))
+    return err
+}
+
+// sourceLine returns the given 1 based line without its line ending,
+// or "" past the end of the source.
+func sourceLine(source string, line int) string {
+    lines := strings.Split(source, "\n")
+    if line < 1 || line > len(lines) {
+        return ""
+    }
+    return strings.TrimSuffix(lines[line - 1], "\r")
+}
+
+// caret returns where the underline starts on the rendered line and how
+// wide it is. Spans reaching past the line are cut at its end.
+func caret(line string, pos, end token.Position) (int, int) {
+    runes := []rune(line)
+    from := min(pos.Column - 1, len(runes))
+    to := from + 1
+    if end.IsValid() && end.Line == pos.Line && end.Column > pos.Column {
+        to = min(end.Column - 1, len(runes))
+    } else if end.IsValid() && end.Line > pos.Line {
+        to = len(runes)
+    }
+
+    start := displayWidth(runes[:from])
+    width := displayWidth(runes[from:max(to, from)])
+    return start, max(width, 1)
+}
+
+func displayWidth(runes []rune) int {
+    width := 0
+    for _, r := range runes {
+        if r == '\t' {
+            width += tabWidth
+        } else {
+            width += 1
+        }
+    }
+    return width
+}
+
+func expandTabs(line string) string {
+    return strings.ReplaceAll(line, "\t", strings.Repeat(" ", tabWidth))
+}
+
+// IsTerminal reports whether w is a terminal that should get colored
+// output. Setting the NO_COLOR environment variable turns colors off.
+func IsTerminal(w io.Writer) bool {
+    if os.Getenv("NO_COLOR") != "" {
+        return false
+    }
+    f, ok := w.(*os.File)
+    if !ok {
+        return false
+    }
+    info, err := f.Stat()
+    if err != nil {
+        return false
+    }
+    return info.Mode() & os.ModeCharDevice != 0
+}
@@ -0,0 +1,122 @@
+package diagnostic
+
+import (
+	"bytes"
+	"monke/lexer"
+	"monke/parser"
+	"monke/token"
+	"strings"
+	"testing"
+)
+
+func TestRenderParseError(t *testing.T) {
+    source := "let x = 5;\nlet y 10;\n"
+
+    p := parser.New(lexer.New(source, lexer.WithFilename("main.monke")))
+    p.ParseProgram()
+
+    var out bytes.Buffer
+    Renderer{}.Render(&out, source, FromParseError(p.ErrorList()[0]))
+
+    expected := `error[unexpected-token]: expected next token '=', got 'INT'
+ --> main.monke:2:7
+  |
+2 | let y 10;
+  |       ^^
+`
+    if out.String() != expected {
+        t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+    }
+}
+
+func TestRenderNotesAndHelp(t *testing.T) {
+    source := "let x = 1;\n\tlet values = [1, 2, 3];"
+
+    d := Diagnostic{
+        Severity: Warning,
+        Code: "unused",
+        Message: "values is never used",
+        Pos: token.Position{Line: 2, Column: 6},
+        End: token.Position{Line: 2, Column: 12},
+        Notes: []string{"declared here"},
+        Help: "remove the binding",
+    }
+
+    var out bytes.Buffer
+    Renderer{}.Render(&out, source, d)
+
+    expected := `warning[unused]: values is never used
+ --> 2:6
+  |
+2 |     let values = [1, 2, 3];
+  |         ^^^^^^
+  = note: declared here
+  = help: remove the binding
+`
+    if out.String() != expected {
+        t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+    }
+}
+
+func TestRenderSpans(t *testing.T) {
+    tests := []struct {
+        source string
+        pos token.Position
+        end token.Position
+        expected string
+    }{
+        // single character
+        {"abc", token.Position{Line: 1, Column: 2}, token.Position{}, "  |  ^\n"},
+        // past the end of the line, like EOF
+        {"abc", token.Position{Line: 1, Column: 4}, token.Position{Line: 1, Column: 4}, "  |    ^\n"},
+        // running into the next line
+        {"abc\ndef", token.Position{Line: 1, Column: 2}, token.Position{Line: 2, Column: 2}, "  |  ^^\n"},
+        // unicode counts characters, not bytes
+        {"größe = 1", token.Position{Line: 1, Column: 7}, token.Position{Line: 1, Column: 8}, "  |       ^\n"},
+        // past the last line
+        {"abc\n", token.Position{Line: 2, Column: 1}, token.Position{}, "  | ^\n"},
+    }
+
+    for _, test := range tests {
+        var out bytes.Buffer
+        Renderer{}.Render(&out, test.source, Diagnostic{Severity: Error, Message: "m", Pos: test.pos, End: test.end})
+
+        lines := strings.SplitAfter(out.String(), "\n")
+        caretLine := lines[4]
+        if caretLine != test.expected {
+            t.Errorf("expected caret line %q, got %q in:\n%s", test.expected, caretLine, out.String())
+        }
+    }
+}
+
+func TestRenderColor(t *testing.T) {
+    d := Diagnostic{Severity: Error, Message: "boom", Pos: token.Position{Line: 1, Column: 1}}
+
+    var plain, colored bytes.Buffer
+    Renderer{}.Render(&plain, "x", d)
+    Renderer{Color: true}.Render(&colored, "x", d)
+
+    if strings.Contains(plain.String(), "\x1b[") {
+        t.Errorf("expected no escape codes, got %q", plain.String())
+    }
+    if !strings.HasPrefix(colored.String(), "\x1b[1;31merror\x1b[0m") {
+        t.Errorf("expected red error header, got %q", colored.String())
+    }
+}
+
+func TestRenderErrors(t *testing.T) {
+    source := "let a 1;\nlet b 2;\n"
+
+    p := parser.New(lexer.New(source))
+    p.ParseProgram()
+
+    var out bytes.Buffer
+    Renderer{}.RenderErrors(&out, source, p.ErrorList())
+
+    if strings.Count(out.String(), "error[unexpected-token]") != 2 {
+        t.Errorf("expected 2 rendered errors, got:\n%s", out.String())
+    }
+    if !strings.HasSuffix(out.String(), "error: could not parse due to 2 previous errors\n") {
+        t.Errorf("expected summary line, got:\n%s", out.String())
+    }
+}
@@ -1,85 +1,421 @@
 package lexer
 
 import (
+	"bufio"
+	"fmt"
+	"io"
 	"monke/token"
+	"sort"
+	"strconv"
+	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 type Lexer struct {
-    input string
-    position int
-    readPosition int
-    ch byte
+    reader *bufio.Reader
+    filename string
+    keepComments bool
+
+    // operators added with AddOperator, longest first, and the ones
+    // spelled like identifiers
+    operators []string
+    keywords map[string]token.TokenType
+
+    position int     // byte offset of ch
+    readPosition int // byte offset of the rune after ch
+    ch rune
+    chBytes []byte   // ch as found in the input, may be invalid UTF-8
+    eof bool
+
+    // bytes consumed since the start of the current token,
+    // literals are cut out of it
+    text []byte
+
+    // line and column of ch
+    line int
+    column int
+
+    errors []Error
+}
+
+// Error is a problem found while reading the input, like an unterminated
+// string. The lexer keeps going after an error, usually by emitting an
+// ILLEGAL token in place of the broken input.
+type Error struct {
+    Pos token.Position
+    Msg string
+}
+
+func (e Error) Error() string {
+    return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+type Option func(*Lexer)
+
+// WithFilename sets the file name reported in token positions.
+func WithFilename(filename string) Option {
+    return func(l *Lexer) {
+        l.filename = filename
+    }
+}
+
+// WithComments makes the lexer emit COMMENT tokens instead of
+// skipping comments, for tools that need to keep them.
+func WithComments() Option {
+    return func(l *Lexer) {
+        l.keepComments = true
+    }
+}
+
+// WithOperators makes the lexer recognize extra operator symbols,
+// see AddOperator.
+func WithOperators(symbols ...string) Option {
+    return func(l *Lexer) {
+        for _, symbol := range symbols {
+            l.AddOperator(symbol)
+        }
+    }
+}
+
+func New(input string, opts ...Option) *Lexer {
+    return NewReader(strings.NewReader(input), opts...)
 }
 
-func New(input string) *Lexer {
-    l := &Lexer{input: input}
+// NewReader creates a lexer that reads its input incrementally, so large
+// files and pipes never have to be held in memory as a whole. It produces
+// the same tokens as New would for the same input.
+func NewReader(r io.Reader, opts ...Option) *Lexer {
+    l := &Lexer{reader: bufio.NewReader(r), line: 1}
+    for _, opt := range opts {
+        opt(l)
+    }
     l.readChar()
     return l
 }
 
+// Errors returns all errors found so far, in input order.
+func (l *Lexer) Errors() []Error {
+    return l.errors
+}
+
+// AddOperator makes the lexer emit symbol as a single token whose type is
+// the symbol itself, for operators a parser was extended with. Symbols
+// spelled like identifiers, like "mod", become keywords. Others take
+// precedence over the built-in tokens they start with, so "|>" is one
+// token rather than an error about '|'. The symbol has to be added before
+// the lexer reads past its first use.
+//
+// AddOperator panics if symbol is empty, contains spaces, starts like a
+// number, string or comment, or mixes identifier and other characters.
+// Words also can not be keywords or spell a built-in token type, like
+// "EOF", which the added token would be mistaken for.
+func (l *Lexer) AddOperator(symbol string) {
+    if _, ok := token.LookupSymbol(symbol); ok {
+        return
+    }
+    if err := checkOperator(symbol); err != "" {
+        panic(fmt.Sprintf("lexer: invalid operator %q: %s", symbol, err))
+    }
+
+    first, _ := utf8.DecodeRuneInString(symbol)
+    if token.IsIdentStart(first) {
+        if l.keywords == nil {
+            l.keywords = map[string]token.TokenType{}
+        }
+        l.keywords[symbol] = token.TokenType(symbol)
+        return
+    }
+
+    for _, op := range l.operators {
+        if op == symbol {
+            return
+        }
+    }
+    l.operators = append(l.operators, symbol)
+    sort.SliceStable(l.operators, func(i, j int) bool {
+        return len(l.operators[i]) > len(l.operators[j])
+    })
+}
+
+// checkOperator describes what makes symbol unusable as an operator,
+// or returns "" if nothing does.
+func checkOperator(symbol string) string {
+    if symbol == "" {
+        return "empty symbol"
+    }
+    if strings.HasPrefix(symbol, "//") || strings.HasPrefix(symbol, "/*") {
+        return "starts a comment"
+    }
+
+    first, _ := utf8.DecodeRuneInString(symbol)
+    if isDigit(first) || first == '"' {
+        return "starts like a literal"
+    }
+    word := token.IsIdentStart(first)
+    for _, ch := range symbol {
+        if unicode.IsSpace(ch) || ch == utf8.RuneError {
+            return "contains spaces or invalid characters"
+        }
+        if word != token.IsIdentPart(ch) {
+            return "mixes identifier and other characters"
+        }
+    }
+    if word && token.LookupIdent(symbol) != token.IDENT {
+        return "is a keyword"
+    }
+    if token.IsBuiltin(token.TokenType(symbol)) {
+        return "is the name of a built-in token type"
+    }
+    return ""
+}
+
+func (l *Lexer) addError(pos token.Position, format string, args ...any) {
+    l.errors = append(l.errors, Error{Pos: pos, Msg: fmt.Sprintf(format, args...)})
+}
+
 func (l *Lexer) readChar() {
-    l.ch = l.peekChar()
-    l.position = l.readPosition;
-    l.readPosition += 1;
+    // already past the end, stay on EOF
+    if l.eof {
+        return
+    }
+    if l.ch == '\n' {
+        l.line += 1
+        l.column = 0
+    }
+    l.column += 1
+    l.text = append(l.text, l.chBytes...)
+
+    r, raw := l.peekRune()
+    l.reader.Discard(len(raw))
+    l.ch = r
+    l.chBytes = append(l.chBytes[:0], raw...)
+    l.position = l.readPosition
+    l.readPosition += len(raw)
+    l.eof = len(raw) == 0
 }
 
-func (l *Lexer) peekChar() byte {
-    c := byte(0)
-    if l.readPosition < len(l.input) {
-        c = l.input[l.readPosition]
+func (l *Lexer) peekChar() rune {
+    r, _ := l.peekRune()
+    return r
+}
+
+// peekRune decodes the next rune of the reader without consuming it and
+// returns it with its encoding. It returns 0 and no bytes at the end of
+// input. Only the bytes of that one rune are waited for, so reading from
+// an interactive stream does not block on input that is not needed yet.
+func (l *Lexer) peekRune() (rune, []byte) {
+    if l.eof {
+        return 0, nil
+    }
+    buf, err := l.reader.Peek(1)
+    if len(buf) == 1 && buf[0] >= utf8.RuneSelf {
+        buf, err = l.reader.Peek(encodedLen(buf[0]))
+    }
+    if len(buf) == 0 {
+        if err != nil && err != io.EOF {
+            l.addError(l.pos(), "read error: %v", err)
+        }
+        return 0, nil
+    }
+    r, width := utf8.DecodeRune(buf)
+    return r, buf[:width]
+}
+
+// encodedLen returns the length of a UTF-8 sequence from its first byte.
+// Invalid first bytes count as 1, DecodeRune reports them as RuneError.
+func encodedLen(b byte) int {
+    switch {
+    case b >= 0xF0:
+        return 4
+    case b >= 0xE0:
+        return 3
+    case b >= 0xC0:
+        return 2
     }
-    return c
+    return 1
+}
+
+// isInvalidRune reports whether ch stands for a malformed UTF-8 byte
+// rather than a literally encoded U+FFFD.
+func (l *Lexer) isInvalidRune() bool {
+    return l.ch == utf8.RuneError && len(l.chBytes) == 1
+}
+
+func (l *Lexer) atEOF() bool {
+    return l.eof
+}
+
+// mark returns the current length of text, characters read from now
+// on can be collected with textFrom.
+func (l *Lexer) mark() int {
+    return len(l.text)
+}
+
+func (l *Lexer) textFrom(mark int) string {
+    return string(l.text[mark:])
 }
 
 func (l *Lexer) skipWhitespace() {
-    for unicode.IsSpace(rune(l.ch)) {
+    for unicode.IsSpace(l.ch) {
         l.readChar()
     }
 }
-func newToken(tokenType token.TokenType, c byte) token.Token {
+func newToken(tokenType token.TokenType, c rune) token.Token {
     return token.Token{
         Type: tokenType,
         Literal: string(c),
     }
 }
+
+// readTwoCharToken reads a token made of ch and the character after it,
+// the caller has already checked that the second one matches.
+func (l *Lexer) readTwoCharToken(tokenType token.TokenType) token.Token {
+    first := l.ch
+    l.readChar()
+    return token.Token{
+        Type: tokenType,
+        Literal: string(first) + string(l.ch),
+    }
+}
+
+func (l *Lexer) pos() token.Position {
+    return token.Position{
+        Filename: l.filename,
+        Offset: l.position,
+        Line: l.line,
+        Column: l.column,
+    }
+}
+
 func (l *Lexer) NextToken() token.Token {
-    l.skipWhitespace()
+    for {
+        l.skipWhitespace()
+        l.text = l.text[:0]
+
+        start := l.pos()
+        tok := l.readToken()
+        tok.Pos = start
+        tok.End = l.pos()
+
+        if tok.Type != token.COMMENT || l.keepComments {
+            return tok
+        }
+    }
+}
 
+func (l *Lexer) readToken() token.Token {
     var tok token.Token
+    if l.atEOF() {
+        tok.Type = token.EOF
+        return tok
+    }
+    if tok, ok := l.readOperator(); ok {
+        return tok
+    }
+
     switch l.ch {
         case '=':
-            tok = newToken(token.ASSIGN, l.ch)
-            if l.peekChar() == '=' {
-                first := l.ch
-                l.readChar()
-                tok.Type = token.EQ
-                tok.Literal = string(first) + string(l.ch)
+            switch l.peekChar() {
+            case '=':
+                tok = l.readTwoCharToken(token.EQ)
+            case '>':
+                tok = l.readTwoCharToken(token.FAT_ARROW)
+            default:
+                tok = newToken(token.ASSIGN, l.ch)
             }
         case '!':
-            tok = newToken(token.BANG, l.ch)
             if l.peekChar() == '=' {
-                first := l.ch
-                l.readChar()
-                tok.Type = token.NEQ
-                tok.Literal = string(first) + string(l.ch)
+                tok = l.readTwoCharToken(token.NEQ)
+            } else {
+                tok = newToken(token.BANG, l.ch)
             }
         case '+':
-            tok = newToken(token.PLUS, l.ch)
+            if l.peekChar() == '=' {
+                tok = l.readTwoCharToken(token.PLUS_ASSIGN)
+            } else {
+                tok = newToken(token.PLUS, l.ch)
+            }
         case '-':
-            tok = newToken(token.MINUS, l.ch)
+            switch l.peekChar() {
+            case '=':
+                tok = l.readTwoCharToken(token.MINUS_ASSIGN)
+            case '>':
+                tok = l.readTwoCharToken(token.ARROW)
+            default:
+                tok = newToken(token.MINUS, l.ch)
+            }
         case '*':
-            tok = newToken(token.ASTERISK, l.ch)
+            switch l.peekChar() {
+            case '*':
+                tok = l.readTwoCharToken(token.POWER)
+            case '=':
+                tok = l.readTwoCharToken(token.ASTERISK_ASSIGN)
+            default:
+                tok = newToken(token.ASTERISK, l.ch)
+            }
+        case '%':
+            tok = newToken(token.PERCENT, l.ch)
+        case '&':
+            if l.peekChar() == '&' {
+                tok = l.readTwoCharToken(token.AND)
+            } else {
+                l.addError(l.pos(), "unexpected character '&', did you mean '&&'?")
+                tok = newToken(token.ILLEGAL, l.ch)
+            }
+        case '|':
+            if l.peekChar() == '|' {
+                tok = l.readTwoCharToken(token.OR)
+            } else {
+                l.addError(l.pos(), "unexpected character '|', did you mean '||'?")
+                tok = newToken(token.ILLEGAL, l.ch)
+            }
         case '/':
-            tok = newToken(token.SLASH, l.ch)
+            switch l.peekChar() {
+            case '/':
+                tok.Type = token.COMMENT
+                tok.Literal = l.readLineComment()
+                return tok
+            case '*':
+                start := l.mark()
+                tok.Type = token.COMMENT
+                if !l.readBlockComment() {
+                    tok.Type = token.ILLEGAL
+                }
+                tok.Literal = l.textFrom(start)
+                return tok
+            case '=':
+                tok = l.readTwoCharToken(token.SLASH_ASSIGN)
+            default:
+                tok = newToken(token.SLASH, l.ch)
+            }
         case '<':
-            tok = newToken(token.LT, l.ch)
+            if l.peekChar() == '=' {
+                tok = l.readTwoCharToken(token.LTE)
+            } else {
+                tok = newToken(token.LT, l.ch)
+            }
         case '>':
-            tok = newToken(token.GT, l.ch)
+            if l.peekChar() == '=' {
+                tok = l.readTwoCharToken(token.GTE)
+            } else {
+                tok = newToken(token.GT, l.ch)
+            }
         case ',':
             tok = newToken(token.COMMA, l.ch)
         case ';':
             tok = newToken(token.SEMICOLON, l.ch)
+        case ':':
+            tok = newToken(token.COLON, l.ch)
+        case '.':
+            if l.lookingAt("...") {
+                l.readChar()
+                l.readChar()
+                tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+            } else {
+                l.addError(l.pos(), "unexpected character '.'")
+                tok = newToken(token.ILLEGAL, l.ch)
+            }
         case '(':
             tok = newToken(token.LPAREN, l.ch)
         case ')':
@@ -88,22 +424,40 @@ func (l *Lexer) NextToken() token.Token {
             tok = newToken(token.LBRACE, l.ch)
         case '}':
             tok = newToken(token.RBRACE, l.ch)
-        case 0:
-            tok.Type = token.EOF
-            tok.Literal = ""
+        case '[':
+            tok = newToken(token.LBRACKET, l.ch)
+        case ']':
+            tok = newToken(token.RBRACKET, l.ch)
+        case '"':
+            start := l.mark()
+            value, ok := l.readString()
+            if ok {
+                tok.Type = token.STRING
+                tok.Literal = value
+            } else {
+                tok.Type = token.ILLEGAL
+                tok.Literal = l.textFrom(start)
+            }
+            return tok
         default:
-            if token.IsIdentByte(l.ch) {
+            if l.isInvalidRune() {
+                l.addError(l.pos(), "invalid UTF-8 encoding")
+                tok.Type = token.ILLEGAL
+                tok.Literal = string(l.chBytes)
+            } else if token.IsIdentStart(l.ch) {
                 literal := l.readIdent()
                 tok.Type = token.LookupIdent(literal)
+                if keyword, ok := l.keywords[literal]; ok {
+                    tok.Type = keyword
+                }
                 tok.Literal = literal
                 return tok
 
-            } else if unicode.IsDigit(rune(l.ch)) {
-                tok.Type = token.INT
-                tok.Literal = l.readInt()
-                return tok
+            } else if isDigit(l.ch) {
+                return l.readNumber()
 
             } else {
+                l.addError(l.pos(), "unexpected character %q", l.ch)
                 tok = newToken(token.ILLEGAL, l.ch)
             }
     }
@@ -111,19 +465,310 @@ func (l *Lexer) NextToken() token.Token {
     return tok
 }
 
+// readOperator reads the longest added operator starting at ch. A longer
+// built-in symbol starting there, like "&&" for an added "&" or "..." for
+// an added ".", still wins over it and is left to NextToken. It reports
+// false if no added operator is read.
+func (l *Lexer) readOperator() (token.Token, bool) {
+    for _, symbol := range l.operators {
+        if !l.lookingAt(symbol) {
+            continue
+        }
+        if len(l.longestBuiltin()) > len(symbol) {
+            return token.Token{}, false
+        }
+        for range symbol {
+            l.readChar()
+        }
+        return token.Token{Type: token.TokenType(symbol), Literal: symbol}, true
+    }
+    return token.Token{}, false
+}
+
+// longestBuiltin returns the longest built-in symbol starting at ch, or ""
+// if there is none.
+func (l *Lexer) longestBuiltin() string {
+    longest := ""
+    for _, symbol := range token.Symbols() {
+        if len(symbol) > len(longest) && l.lookingAt(symbol) {
+            longest = symbol
+        }
+    }
+    return longest
+}
+
+// lookingAt reports whether the input continues with symbol from ch on.
+// Input after ch is only waited for once ch matches.
+func (l *Lexer) lookingAt(symbol string) bool {
+    if !strings.HasPrefix(symbol, string(l.chBytes)) || l.isInvalidRune() {
+        return false
+    }
+    rest := len(symbol) - len(l.chBytes)
+    buf, _ := l.reader.Peek(rest)
+    return string(buf) == symbol[len(l.chBytes):]
+}
+
 func (l *Lexer) readIdent() string {
-    identStart := l.position
-    for token.IsIdentByte(l.ch) {
+    identStart := l.mark()
+    for token.IsIdentPart(l.ch) {
         l.readChar()
     }
-    return l.input[identStart:l.position]
+    return l.textFrom(identStart)
 }
 
 
-func (l *Lexer) readInt() string {
-    identStart := l.position
-    for unicode.IsDigit(rune(l.ch)) {
+// readNumber reads an integer or float literal:
+//
+//     decimal  123, 1_000_000
+//     hex      0xff, 0XFF
+//     octal    0o17
+//     binary   0b1010
+//     float    3.14, 1e-9, 2.5E+3
+//
+// Underscores may only separate digits, or follow a base prefix like in
+// 0x_ff. A malformed literal is reported and returned as ILLEGAL.
+func (l *Lexer) readNumber() token.Token {
+    start := l.mark()
+    pos := l.pos()
+    tok := token.Token{Type: token.INT}
+
+    var msg string
+    check := func(m string) {
+        if msg == "" {
+            msg = m
+        }
+    }
+
+    base := 10
+    if l.ch == '0' {
+        switch l.peekChar() {
+        case 'x', 'X':
+            base = 16
+        case 'o', 'O':
+            base = 8
+        case 'b', 'B':
+            base = 2
+        }
+        if base != 10 {
+            l.readChar()
+            l.readChar()
+        }
+    }
+
+    digits := l.readDigits(base == 16)
+    check(checkDigits(digits, base, base != 10))
+
+    if base == 10 && l.ch == '.' && isDigit(l.peekChar()) {
+        tok.Type = token.FLOAT
+        l.readChar()
+        check(checkDigits(l.readDigits(false), 10, false))
+    }
+    if base == 10 && (l.ch == 'e' || l.ch == 'E') {
+        tok.Type = token.FLOAT
         l.readChar()
+        if l.ch == '+' || l.ch == '-' {
+            l.readChar()
+        }
+        exponent := l.readDigits(false)
+        if exponent == "" {
+            check("exponent has no digits")
+        } else {
+            check(checkDigits(exponent, 10, false))
+        }
+    }
+    // 0123.5 is fine as a float, but as an integer it looks like C octal
+    if tok.Type == token.INT && base == 10 && len(digits) > 1 && digits[0] == '0' {
+        check("leading zeros in decimal literal, use the 0o prefix for octal")
+    }
+
+    if token.IsIdentPart(l.ch) {
+        check(fmt.Sprintf("invalid character %q in number literal", l.ch))
+        for token.IsIdentPart(l.ch) {
+            l.readChar()
+        }
+    }
+
+    tok.Literal = l.textFrom(start)
+    if msg != "" {
+        l.addError(pos, "invalid number literal %q: %s", tok.Literal, msg)
+        tok.Type = token.ILLEGAL
+    }
+    return tok
+}
+
+// readDigits reads a run of decimal digits and underscores, also taking
+// hex letters if asked to. Digits invalid for the base are checked later,
+// so that 0b102 is read and reported as a single literal.
+func (l *Lexer) readDigits(hex bool) string {
+    start := l.mark()
+    for isDigit(l.ch) || l.ch == '_' || (hex && isHexDigit(l.ch)) {
+        l.readChar()
+    }
+    return l.textFrom(start)
+}
+
+// checkDigits describes what is wrong with a group of digits, or returns ""
+// if nothing is.
+func checkDigits(digits string, base int, leadingUnderscore bool) string {
+    if strings.Trim(digits, "_") == "" {
+        return fmt.Sprintf("%s literal has no digits", baseNames[base])
     }
-    return l.input[identStart:l.position]
+    for i, ch := range digits {
+        if ch == '_' {
+            first := i == 0 && !leadingUnderscore
+            last := i == len(digits) - 1
+            if first || last || digits[i + 1] == '_' {
+                return "'_' must separate successive digits"
+            }
+            continue
+        }
+        value, _ := strconv.ParseInt(string(ch), 16, 8)
+        if int(value) >= base {
+            return fmt.Sprintf("invalid digit %q in %s literal", ch, baseNames[base])
+        }
+    }
+    return ""
+}
+
+var baseNames = map[int]string{
+    2: "binary",
+    8: "octal",
+    10: "decimal",
+    16: "hexadecimal",
+}
+
+// numbers are ASCII only, unicode digits are only valid inside identifiers
+func isDigit(ch rune) bool {
+    return ch >= '0' && ch <= '9'
+}
+
+// readLineComment reads a comment from "//" up to the end of the line,
+// the newline itself is left to skipWhitespace.
+func (l *Lexer) readLineComment() string {
+    start := l.mark()
+    for l.ch != '\n' && !l.atEOF() {
+        l.readChar()
+    }
+    return l.textFrom(start)
+}
+
+// readBlockComment reads a "/* ... */" comment. Block comments do not nest,
+// a "/*" inside one is reported but still has to be closed, so that the
+// rest of the input is not lexed as code. It reports false if the comment
+// is not terminated before the end of input.
+func (l *Lexer) readBlockComment() bool {
+    start := l.pos()
+    l.readChar()
+    l.readChar()
+
+    depth := 1
+    for depth > 0 {
+        switch {
+        case l.atEOF():
+            l.addError(start, "unterminated block comment")
+            return false
+        case l.ch == '*' && l.peekChar() == '/':
+            depth -= 1
+            l.readChar()
+            l.readChar()
+        case l.ch == '/' && l.peekChar() == '*':
+            l.addError(l.pos(), "nested block comments are not supported")
+            depth += 1
+            l.readChar()
+            l.readChar()
+        default:
+            l.readChar()
+        }
+    }
+    return true
+}
+
+// readString reads a double quoted string starting at the opening quote and
+// returns its value with escape sequences resolved. It reports false if the
+// string is not terminated before the end of input.
+func (l *Lexer) readString() (string, bool) {
+    start := l.pos()
+    var out strings.Builder
+
+    l.readChar()
+    for {
+        switch {
+        case l.atEOF():
+            l.addError(start, "unterminated string literal")
+            return out.String(), false
+        case l.ch == '"':
+            l.readChar()
+            return out.String(), true
+        case l.ch == '\\':
+            l.readEscape(&out)
+        case l.isInvalidRune():
+            l.addError(l.pos(), "invalid UTF-8 encoding in string literal")
+            l.readChar()
+        default:
+            out.WriteRune(l.ch)
+            l.readChar()
+        }
+    }
+}
+
+// readEscape reads an escape sequence starting at the backslash:
+// \n, \t, \", \\ or \u{...} with 1 to 6 hex digits.
+func (l *Lexer) readEscape(out *strings.Builder) {
+    start := l.pos()
+    l.readChar()
+
+    switch l.ch {
+    case 'n':
+        out.WriteRune('\n')
+    case 't':
+        out.WriteRune('\t')
+    case '"', '\\':
+        out.WriteRune(l.ch)
+    case 'u':
+        l.readUnicodeEscape(start, out)
+        return
+    default:
+        if l.atEOF() {
+            // reported as unterminated string
+            return
+        }
+        l.addError(start, "unknown escape sequence '\\%c'", l.ch)
+    }
+    l.readChar()
+}
+
+func (l *Lexer) readUnicodeEscape(start token.Position, out *strings.Builder) {
+    l.readChar()
+    if l.ch != '{' {
+        l.addError(start, "expected '{' after '\\u'")
+        return
+    }
+    l.readChar()
+
+    digitsStart := l.mark()
+    for isHexDigit(l.ch) {
+        l.readChar()
+    }
+    digits := l.textFrom(digitsStart)
+
+    if l.ch != '}' {
+        l.addError(start, "expected '}' to close unicode escape")
+        return
+    }
+    l.readChar()
+
+    if len(digits) == 0 || len(digits) > 6 {
+        l.addError(start, "unicode escape must have 1 to 6 hex digits")
+        return
+    }
+    value, _ := strconv.ParseUint(digits, 16, 32)
+    if !utf8.ValidRune(rune(value)) {
+        l.addError(start, "invalid unicode code point U+%X", value)
+        return
+    }
+    out.WriteRune(rune(value))
+}
+
+func isHexDigit(ch rune) bool {
+    return isDigit(ch) || (ch >= 'a' && ch <= 'f') || (ch >= 'A' && ch <= 'F')
 }
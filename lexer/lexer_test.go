@@ -1,9 +1,13 @@
 package lexer
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"monke/token"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 
@@ -16,7 +20,7 @@ func TestNextToken(t *testing.T) {
         };
         let result = add(five, ten);
 
-        !-/*<>;
+        !-/ *<>;
 
         if (5 < 10) {
             return true;
@@ -126,3 +130,515 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+    input := "let x = 5;\n  x == 10\n"
+
+    tests := []struct {
+        expectedLiteral string
+        line, column    int
+        offset, end     int
+    }{
+        {"let", 1, 1, 0, 3},
+        {"x", 1, 5, 4, 5},
+        {"=", 1, 7, 6, 7},
+        {"5", 1, 9, 8, 9},
+        {";", 1, 10, 9, 10},
+        {"x", 2, 3, 13, 14},
+        {"==", 2, 5, 15, 17},
+        {"10", 2, 8, 18, 20},
+        {"", 3, 1, 21, 21},
+        {"", 3, 1, 21, 21},
+    }
+
+    l := New(input, WithFilename("a.monke"))
+
+    for i, test := range tests {
+        tok := l.NextToken()
+        if tok.Literal != test.expectedLiteral {
+            t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+                i, test.expectedLiteral, tok.Literal)
+        }
+        if tok.Pos.Filename != "a.monke" {
+            t.Fatalf("tests[%d] - filename wrong. got=%q", i, tok.Pos.Filename)
+        }
+        if tok.Pos.Line != test.line || tok.Pos.Column != test.column {
+            t.Fatalf("tests[%d] - position wrong. expected=%d:%d, got=%s",
+                i, test.line, test.column, tok.Pos)
+        }
+        if tok.Pos.Offset != test.offset || tok.End.Offset != test.end {
+            t.Fatalf("tests[%d] - span wrong. expected=[%d, %d), got=[%d, %d)",
+                i, test.offset, test.end, tok.Pos.Offset, tok.End.Offset)
+        }
+    }
+}
+
+func TestUnicodeIdentifiers(t *testing.T) {
+    input := "let größe = 变量_1 + _x9;\n größe € \xff1"
+
+    tests := []struct {
+        expectedType    token.TokenType
+        expectedLiteral string
+        line, column    int
+    }{
+        {token.LET, "let", 1, 1},
+        {token.IDENT, "größe", 1, 5},
+        {token.ASSIGN, "=", 1, 11},
+        {token.IDENT, "变量_1", 1, 13},
+        {token.PLUS, "+", 1, 18},
+        {token.IDENT, "_x9", 1, 20},
+        {token.SEMICOLON, ";", 1, 23},
+        {token.IDENT, "größe", 2, 2},
+        {token.ILLEGAL, "€", 2, 8},
+        {token.ILLEGAL, "\xff", 2, 10},
+        {token.INT, "1", 2, 11},
+        {token.EOF, "", 2, 12},
+    }
+
+    l := New(input)
+
+    for i, test := range tests {
+        tok := l.NextToken()
+
+        if tok.Type != test.expectedType {
+            t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+                i, test.expectedType, tok.Type)
+        }
+        if tok.Literal != test.expectedLiteral {
+            t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+                i, test.expectedLiteral, tok.Literal)
+        }
+        if tok.Pos.Line != test.line || tok.Pos.Column != test.column {
+            t.Fatalf("tests[%d] - position wrong. expected=%d:%d, got=%s",
+                i, test.line, test.column, tok.Pos)
+        }
+    }
+}
+
+func TestStringLiterals(t *testing.T) {
+    tests := []struct {
+        input           string
+        expectedType    token.TokenType
+        expectedLiteral string
+        expectedErrors  int
+    }{
+        {`"foo bar"`, token.STRING, "foo bar", 0},
+        {`""`, token.STRING, "", 0},
+        {`"a\nb\tc"`, token.STRING, "a\nb\tc", 0},
+        {`"say \"hi\" \\o/"`, token.STRING, `say "hi" \o/`, 0},
+        {`"\u{48}\u{e9}\u{1F600}"`, token.STRING, "Hé😀", 0},
+        {`"héllo wörld"`, token.STRING, "héllo wörld", 0},
+        {"\"two\nlines\"", token.STRING, "two\nlines", 0},
+        {`"bad \q escape"`, token.STRING, "bad  escape", 1},
+        {`"\u{}"`, token.STRING, "", 1},
+        {`"\u{110000}"`, token.STRING, "", 1},
+        {`"\u48"`, token.STRING, "48", 1},
+        {`"unterminated`, token.ILLEGAL, `"unterminated`, 1},
+        {`"ends in escape\`, token.ILLEGAL, `"ends in escape\`, 1},
+    }
+
+    for i, test := range tests {
+        l := New(test.input)
+        tok := l.NextToken()
+
+        if tok.Type != test.expectedType {
+            t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+                i, test.expectedType, tok.Type)
+        }
+        if tok.Literal != test.expectedLiteral {
+            t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+                i, test.expectedLiteral, tok.Literal)
+        }
+        if len(l.Errors()) != test.expectedErrors {
+            t.Fatalf("tests[%d] - expected %d errors, got %v",
+                i, test.expectedErrors, l.Errors())
+        }
+        if next := l.NextToken(); next.Type != token.EOF {
+            t.Fatalf("tests[%d] - expected EOF after string, got %q", i, next.Type)
+        }
+    }
+}
+
+func TestUnterminatedStringPosition(t *testing.T) {
+    l := New("let s = \"abc\n")
+    for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+    }
+
+    errors := l.Errors()
+    if len(errors) != 1 {
+        t.Fatalf("expected 1 error, got %v", errors)
+    }
+    if errors[0].Error() != "1:9: unterminated string literal" {
+        t.Fatalf("unexpected error %q", errors[0].Error())
+    }
+}
+
+func TestComments(t *testing.T) {
+    input := `// leading note
+let x = 10 / 2; // trailing
+/* block
+   comment */ x`
+
+    tests := []struct {
+        expectedType    token.TokenType
+        expectedLiteral string
+    }{
+        {token.COMMENT, "// leading note"},
+        {token.LET, "let"},
+        {token.IDENT, "x"},
+        {token.ASSIGN, "="},
+        {token.INT, "10"},
+        {token.SLASH, "/"},
+        {token.INT, "2"},
+        {token.SEMICOLON, ";"},
+        {token.COMMENT, "// trailing"},
+        {token.COMMENT, "/* block\n   comment */"},
+        {token.IDENT, "x"},
+        {token.EOF, ""},
+    }
+
+    withComments := New(input, WithComments())
+    withoutComments := New(input)
+
+    for i, test := range tests {
+        tok := withComments.NextToken()
+        if tok.Type != test.expectedType {
+            t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+                i, test.expectedType, tok.Type)
+        }
+        if tok.Literal != test.expectedLiteral {
+            t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+                i, test.expectedLiteral, tok.Literal)
+        }
+
+        if test.expectedType == token.COMMENT {
+            continue
+        }
+        tok = withoutComments.NextToken()
+        if tok.Type != test.expectedType {
+            t.Fatalf("tests[%d] - tokentype wrong without comments. expected=%q, got=%q",
+                i, test.expectedType, tok.Type)
+        }
+    }
+}
+
+func TestBlockCommentErrors(t *testing.T) {
+    tests := []struct {
+        input          string
+        expectedTypes  []token.TokenType
+        expectedErrors []string
+    }{
+        {
+            "x /* open",
+            []token.TokenType{token.IDENT, token.ILLEGAL, token.EOF},
+            []string{"1:3: unterminated block comment"},
+        },
+        {
+            "/* a /* b */ c */ x",
+            []token.TokenType{token.IDENT, token.EOF},
+            []string{"1:6: nested block comments are not supported"},
+        },
+        {
+            "/* a /* b */ x",
+            []token.TokenType{token.ILLEGAL, token.EOF},
+            []string{
+                "1:6: nested block comments are not supported",
+                "1:1: unterminated block comment",
+            },
+        },
+    }
+
+    for i, test := range tests {
+        l := New(test.input)
+        for j, expected := range test.expectedTypes {
+            tok := l.NextToken()
+            if tok.Type != expected {
+                t.Fatalf("tests[%d][%d] - tokentype wrong. expected=%q, got=%q",
+                    i, j, expected, tok.Type)
+            }
+        }
+
+        errors := l.Errors()
+        if len(errors) != len(test.expectedErrors) {
+            t.Fatalf("tests[%d] - expected errors %v, got %v", i, test.expectedErrors, errors)
+        }
+        for j, e := range errors {
+            if e.Error() != test.expectedErrors[j] {
+                t.Errorf("tests[%d] - expected error %q, got %q", i, test.expectedErrors[j], e.Error())
+            }
+        }
+    }
+}
+
+func TestNumberLiterals(t *testing.T) {
+    tests := []struct {
+        input           string
+        expectedType    token.TokenType
+        expectedLiteral string
+        expectedError   string
+    }{
+        {"0", token.INT, "0", ""},
+        {"1_000_000", token.INT, "1_000_000", ""},
+        {"0xff", token.INT, "0xff", ""},
+        {"0XDead_Beef", token.INT, "0XDead_Beef", ""},
+        {"0x_ff", token.INT, "0x_ff", ""},
+        {"0o17", token.INT, "0o17", ""},
+        {"0b1010", token.INT, "0b1010", ""},
+        {"3.14", token.FLOAT, "3.14", ""},
+        {"1e-9", token.FLOAT, "1e-9", ""},
+        {"2.5E+3", token.FLOAT, "2.5E+3", ""},
+        {"1_000.000_1", token.FLOAT, "1_000.000_1", ""},
+        {"0123.5", token.FLOAT, "0123.5", ""},
+        {"0x", token.ILLEGAL, "0x", `1:1: invalid number literal "0x": hexadecimal literal has no digits`},
+        {"0b", token.ILLEGAL, "0b", `1:1: invalid number literal "0b": binary literal has no digits`},
+        {"1__0", token.ILLEGAL, "1__0", `1:1: invalid number literal "1__0": '_' must separate successive digits`},
+        {"10_", token.ILLEGAL, "10_", `1:1: invalid number literal "10_": '_' must separate successive digits`},
+        {"1._5", token.INT, "1", ""},
+        {"0b102", token.ILLEGAL, "0b102", `1:1: invalid number literal "0b102": invalid digit '2' in binary literal`},
+        {"0o8", token.ILLEGAL, "0o8", `1:1: invalid number literal "0o8": invalid digit '8' in octal literal`},
+        {"0123", token.ILLEGAL, "0123", `1:1: invalid number literal "0123": leading zeros in decimal literal, use the 0o prefix for octal`},
+        {"1e", token.ILLEGAL, "1e", `1:1: invalid number literal "1e": exponent has no digits`},
+        {"1e+_5", token.ILLEGAL, "1e+_5", `1:1: invalid number literal "1e+_5": '_' must separate successive digits`},
+        {"123abc", token.ILLEGAL, "123abc", `1:1: invalid number literal "123abc": invalid character 'a' in number literal`},
+        {"0xfg", token.ILLEGAL, "0xfg", `1:1: invalid number literal "0xfg": invalid character 'g' in number literal`},
+    }
+
+    for i, test := range tests {
+        l := New(test.input)
+        tok := l.NextToken()
+
+        if tok.Type != test.expectedType {
+            t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+                i, test.expectedType, tok.Type)
+        }
+        if tok.Literal != test.expectedLiteral {
+            t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+                i, test.expectedLiteral, tok.Literal)
+        }
+
+        errors := l.Errors()
+        if test.expectedError == "" && len(errors) != 0 {
+            t.Fatalf("tests[%d] - unexpected errors %v", i, errors)
+        }
+        if test.expectedError != "" {
+            if len(errors) != 1 {
+                t.Fatalf("tests[%d] - expected 1 error, got %v", i, errors)
+            }
+            if errors[0].Error() != test.expectedError {
+                t.Fatalf("tests[%d] - expected error %q, got %q",
+                    i, test.expectedError, errors[0].Error())
+            }
+        }
+    }
+}
+
+func TestNewReaderMatchesNew(t *testing.T) {
+    input := `
+        // header
+        let größe = 0x_ff + 1_000 * 3.14e-2;
+        let s = "tab\there \u{1F600}";
+        /* block */ if (größe != 10) { return "€"; }
+        \xff @ "unterminated`
+
+    readers := map[string]func() *Lexer{
+        "reader": func() *Lexer {
+            return NewReader(strings.NewReader(input), WithComments())
+        },
+        "one byte reader": func() *Lexer {
+            return NewReader(iotest.OneByteReader(strings.NewReader(input)), WithComments())
+        },
+    }
+
+    for name, newLexer := range readers {
+        expected := New(input, WithComments())
+        actual := newLexer()
+
+        for i := 0; ; i++ {
+            e := expected.NextToken()
+            a := actual.NextToken()
+            if a != e {
+                t.Fatalf("%s: token %d differs. expected=%+v, got=%+v", name, i, e, a)
+            }
+            if e.Type == token.EOF {
+                break
+            }
+        }
+
+        if fmt.Sprint(actual.Errors()) != fmt.Sprint(expected.Errors()) {
+            t.Fatalf("%s: errors differ. expected=%v, got=%v",
+                name, expected.Errors(), actual.Errors())
+        }
+    }
+}
+
+func TestNewReaderError(t *testing.T) {
+    r := io.MultiReader(strings.NewReader("let x"), iotest.ErrReader(errors.New("broken pipe")))
+    l := NewReader(r)
+
+    expectedTypes := []token.TokenType{token.LET, token.IDENT, token.EOF}
+    for i, expected := range expectedTypes {
+        tok := l.NextToken()
+        if tok.Type != expected {
+            t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, expected, tok.Type)
+        }
+    }
+
+    errs := l.Errors()
+    if len(errs) != 1 || errs[0].Error() != "1:6: read error: broken pipe" {
+        t.Fatalf("expected a read error, got %v", errs)
+    }
+}
+
+func TestOperators(t *testing.T) {
+    input := `<= >= < > % ** * && || += -= *= /= / = == ! != & | [ ] : => ... .. ->`
+
+    tests := []struct {
+        expectedType    token.TokenType
+        expectedLiteral string
+    }{
+        {token.LTE, "<="},
+        {token.GTE, ">="},
+        {token.LT, "<"},
+        {token.GT, ">"},
+        {token.PERCENT, "%"},
+        {token.POWER, "**"},
+        {token.ASTERISK, "*"},
+        {token.AND, "&&"},
+        {token.OR, "||"},
+        {token.PLUS_ASSIGN, "+="},
+        {token.MINUS_ASSIGN, "-="},
+        {token.ASTERISK_ASSIGN, "*="},
+        {token.SLASH_ASSIGN, "/="},
+        {token.SLASH, "/"},
+        {token.ASSIGN, "="},
+        {token.EQ, "=="},
+        {token.BANG, "!"},
+        {token.NEQ, "!="},
+        {token.ILLEGAL, "&"},
+        {token.ILLEGAL, "|"},
+        {token.LBRACKET, "["},
+        {token.RBRACKET, "]"},
+        {token.COLON, ":"},
+        {token.FAT_ARROW, "=>"},
+        {token.ELLIPSIS, "..."},
+        {token.ILLEGAL, "."},
+        {token.ILLEGAL, "."},
+        {token.ARROW, "->"},
+        {token.EOF, ""},
+    }
+
+    l := New(input)
+
+    for i, test := range tests {
+        tok := l.NextToken()
+
+        if tok.Type != test.expectedType {
+            t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+                i, test.expectedType, tok.Type)
+        }
+        if tok.Literal != test.expectedLiteral {
+            t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+                i, test.expectedLiteral, tok.Literal)
+        }
+    }
+
+    if len(l.Errors()) != 4 {
+        t.Fatalf("expected 4 errors, got %v", l.Errors())
+    }
+}
+
+func TestAddedOperators(t *testing.T) {
+    input := `x |> f <=> y <= z & w && v | u || mod modulo`
+
+    tests := []struct {
+        expectedType    token.TokenType
+        expectedLiteral string
+    }{
+        {token.IDENT, "x"},
+        {"|>", "|>"},
+        {token.IDENT, "f"},
+        {"<=>", "<=>"},
+        {token.IDENT, "y"},
+        {token.LTE, "<="},
+        {token.IDENT, "z"},
+        {"&", "&"},
+        {token.IDENT, "w"},
+        {token.AND, "&&"},
+        {token.IDENT, "v"},
+        {"|", "|"},
+        {token.IDENT, "u"},
+        {token.OR, "||"},
+        {"mod", "mod"},
+        {token.IDENT, "modulo"},
+        {token.EOF, ""},
+    }
+
+    l := New(input, WithOperators("|>", "&", "|", "mod", "+"))
+    l.AddOperator("<=>")
+
+    for i, test := range tests {
+        tok := l.NextToken()
+
+        if tok.Type != test.expectedType {
+            t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+                i, test.expectedType, tok.Type)
+        }
+        if tok.Literal != test.expectedLiteral {
+            t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+                i, test.expectedLiteral, tok.Literal)
+        }
+    }
+    if len(l.Errors()) != 0 {
+        t.Errorf("expected no errors, got %v", l.Errors())
+    }
+
+    // other lexers are not affected
+    if tok := New("|>").NextToken(); tok.Type != token.ILLEGAL {
+        t.Errorf("expected ILLEGAL without the operator, got %q", tok.Type)
+    }
+}
+
+func TestAddedOperatorsLoseToLongerBuiltins(t *testing.T) {
+    tests := []struct {
+        expectedType    token.TokenType
+        expectedLiteral string
+    }{
+        {".", "."},
+        {token.ELLIPSIS, "..."},
+        {token.IDENT, "rest"},
+        {".", "."},
+        {".", "."},
+        {"&", "&"},
+        {token.AND, "&&"},
+        {token.EOF, ""},
+    }
+
+    // ".." is not a built-in, "..." still has to be found past it
+    l := New(". ...rest .. & &&", WithOperators(".", "&"))
+
+    for i, test := range tests {
+        tok := l.NextToken()
+
+        if tok.Type != test.expectedType || tok.Literal != test.expectedLiteral {
+            t.Fatalf("tests[%d] - expected %q %q, got %q %q",
+                i, test.expectedType, test.expectedLiteral, tok.Type, tok.Literal)
+        }
+    }
+    if len(l.Errors()) != 0 {
+        t.Errorf("expected no errors, got %v", l.Errors())
+    }
+}
+
+func TestInvalidAddedOperators(t *testing.T) {
+    invalid := []string{"", "a b", "1+", "\"", "//", "a+", "+a", "in", "let", "EOF", "RETURN", "IDENT"}
+    for _, symbol := range invalid {
+        func() {
+            defer func() {
+                if recover() == nil {
+                    t.Errorf("expected AddOperator(%q) to panic", symbol)
+                }
+            }()
+            New("").AddOperator(symbol)
+        }()
+    }
+}
@@ -0,0 +1,182 @@
+package loader
+
+import (
+	"fmt"
+	"monke/ast"
+	"monke/lexer"
+	"monke/parser"
+	"monke/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Module is a parsed source file and the modules it imports.
+type Module struct {
+    Path string // as resolved, relative paths stay relative
+    Program *ast.Program
+    Imports map[string]*Module // by the name they are imported as
+    Exports []string           // names bound by export let, in order
+
+    abs string
+}
+
+// Loader loads modules for one run. Every file is parsed once, modules
+// imported from several places share a single Module.
+type Loader struct {
+    // directories to look for imports in when they are not found next to
+    // the importing file, in order
+    SearchPath []string
+
+    modules map[string]*Module // by absolute path
+    loading []*Module          // the import chain being loaded
+}
+
+func New(searchPath ...string) *Loader {
+    return &Loader{SearchPath: searchPath, modules: map[string]*Module{}}
+}
+
+// ParseError is returned for a module that does not parse.
+type ParseError struct {
+    Path string
+    Errors parser.ErrorList
+}
+
+func (e *ParseError) Error() string {
+    return e.Errors.Error()
+}
+
+// ImportError is an import that could not be loaded. For cycles, Chain
+// lists the modules from the first one in the cycle back to itself.
+type ImportError struct {
+    Pos, End token.Position // span of the imported path
+    Msg string
+    Chain []string
+}
+
+func (e *ImportError) Error() string {
+    return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// Load parses the file at path and, recursively, the modules it imports.
+// Errors are *ParseError or *ImportError, or the error opening path.
+func (l *Loader) Load(path string) (*Module, error) {
+    abs, err := filepath.Abs(path)
+    if err != nil {
+        return nil, err
+    }
+    if module, ok := l.modules[abs]; ok {
+        return module, nil
+    }
+
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+
+    p := parser.New(lexer.NewReader(f, lexer.WithFilename(path)))
+    program := p.ParseProgram()
+    if errors := p.ErrorList(); len(errors) != 0 {
+        return nil, &ParseError{Path: path, Errors: errors}
+    }
+
+    module := &Module{Path: path, Program: program, Imports: map[string]*Module{}, abs: abs}
+    l.loading = append(l.loading, module)
+    defer func() { l.loading = l.loading[:len(l.loading) - 1] }()
+
+    for _, statement := range program.Statements {
+        switch statement := statement.(type) {
+        case *ast.ImportStatement:
+            imported, err := l.loadImport(module, statement)
+            if err != nil {
+                return nil, err
+            }
+            module.Imports[statement.Alias.Value] = imported
+        case *ast.ExportStatement:
+            module.Exports = append(module.Exports, exportedNames(statement)...)
+        }
+    }
+
+    l.modules[abs] = module
+    return module, nil
+}
+
+func (l *Loader) loadImport(from *Module, statement *ast.ImportStatement) (*Module, error) {
+    pos, end := statement.Path.Token.Pos, statement.Path.Token.End
+
+    path, ok := l.resolve(from.Path, statement.Path.Value)
+    if !ok {
+        msg := fmt.Sprintf("cannot find module %q", statement.Path.Value)
+        if len(l.SearchPath) != 0 {
+            msg += fmt.Sprintf(" next to %s or in %s", from.Path, strings.Join(l.SearchPath, ", "))
+        }
+        return nil, &ImportError{Pos: pos, End: end, Msg: msg}
+    }
+
+    abs, err := filepath.Abs(path)
+    if err != nil {
+        return nil, &ImportError{Pos: pos, End: end, Msg: err.Error()}
+    }
+    for i, loading := range l.loading {
+        if loading.abs == abs {
+            chain := []string{}
+            for _, m := range l.loading[i:] {
+                chain = append(chain, m.Path)
+            }
+            chain = append(chain, loading.Path)
+            return nil, &ImportError{
+                Pos: pos,
+                End: end,
+                Msg: "import cycle: " + strings.Join(chain, " -> "),
+                Chain: chain,
+            }
+        }
+    }
+
+    module, err := l.Load(path)
+    if err != nil {
+        if _, ok := err.(*os.PathError); ok {
+            return nil, &ImportError{Pos: pos, End: end, Msg: err.Error()}
+        }
+        return nil, err
+    }
+    return module, nil
+}
+
+// resolve finds the file an import refers to: next to the importing file
+// first, then in each directory of the search path. Absolute paths are
+// taken as they are.
+func (l *Loader) resolve(from string, path string) (string, bool) {
+    if filepath.IsAbs(path) {
+        return path, exists(path)
+    }
+
+    candidates := []string{filepath.Join(filepath.Dir(from), path)}
+    for _, dir := range l.SearchPath {
+        candidates = append(candidates, filepath.Join(dir, path))
+    }
+    for _, candidate := range candidates {
+        if exists(candidate) {
+            return candidate, true
+        }
+    }
+    return "", false
+}
+
+func exists(path string) bool {
+    info, err := os.Stat(path)
+    return err == nil && !info.IsDir()
+}
+
+func exportedNames(statement *ast.ExportStatement) []string {
+    let := statement.Statement
+    if let.Pattern == nil {
+        return []string{let.Name.Value}
+    }
+    names := []string{}
+    for _, name := range ast.BoundNames(let.Pattern) {
+        names = append(names, name.Value)
+    }
+    return names
+}
@@ -0,0 +1,153 @@
+package loader
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates the given files under a new temporary directory and
+// returns the directory.
+func writeFiles(t *testing.T, files map[string]string) string {
+    dir := t.TempDir()
+    for name, source := range files {
+        path := filepath.Join(dir, name)
+        if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+            t.Fatal(err)
+        }
+        if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+            t.Fatal(err)
+        }
+    }
+    return dir
+}
+
+func TestLoadImports(t *testing.T) {
+    dir := writeFiles(t, map[string]string{
+        "main.monke": `import "lib/math.monke" as math; let x = 1;`,
+        "lib/math.monke": `import "util.monke" as util; export let add = fn(a, b) { a + b }; export let [one, two] = [1, 2];`,
+        "lib/util.monke": `export let id = fn(x) { x };`,
+    })
+
+    module, err := New().Load(filepath.Join(dir, "main.monke"))
+    if err != nil {
+        t.Fatalf("unexpected error %v", err)
+    }
+
+    math := module.Imports["math"]
+    if math == nil {
+        t.Fatalf("expected math to be imported, got %v", module.Imports)
+    }
+    if math.Path != filepath.Join(dir, "lib", "math.monke") {
+        t.Errorf("expected math next to main, got %q", math.Path)
+    }
+    if strings.Join(math.Exports, ",") != "add,one,two" {
+        t.Errorf("expected exports add, one, two, got %v", math.Exports)
+    }
+    // relative to lib/math.monke, not to main.monke
+    if util := math.Imports["util"]; util == nil || util.Exports[0] != "id" {
+        t.Errorf("expected util to be imported by math, got %v", math.Imports)
+    }
+}
+
+func TestSearchPath(t *testing.T) {
+    dir := writeFiles(t, map[string]string{
+        "app/main.monke": `import "strings.monke" as strings;`,
+        "first/other.monke": ``,
+        "second/strings.monke": `export let upper = 1;`,
+    })
+
+    l := New(filepath.Join(dir, "first"), filepath.Join(dir, "second"))
+    module, err := l.Load(filepath.Join(dir, "app", "main.monke"))
+    if err != nil {
+        t.Fatalf("unexpected error %v", err)
+    }
+    if imported := module.Imports["strings"]; imported == nil || imported.Path != filepath.Join(dir, "second", "strings.monke") {
+        t.Errorf("expected strings from the search path, got %v", imported)
+    }
+
+    dir = writeFiles(t, map[string]string{"main.monke": `let x = 1;` + "\n" + `import "missing.monke" as m;`})
+    _, err = New("/nowhere").Load(filepath.Join(dir, "main.monke"))
+    importErr, ok := err.(*ImportError)
+    if !ok {
+        t.Fatalf("expected *ImportError, got %T %v", err, err)
+    }
+    if importErr.Pos.Line != 2 || importErr.Pos.Column != 8 {
+        t.Errorf("expected error at the import path 2:8, got %s", importErr.Pos)
+    }
+    if !strings.Contains(importErr.Msg, `cannot find module "missing.monke"`) || !strings.Contains(importErr.Msg, "/nowhere") {
+        t.Errorf("unexpected message %q", importErr.Msg)
+    }
+}
+
+func TestImportCycle(t *testing.T) {
+    dir := writeFiles(t, map[string]string{
+        "a.monke": `import "b.monke" as b;`,
+        "b.monke": `import "c.monke" as c;`,
+        "c.monke": `import "b.monke" as b;`,
+    })
+
+    _, err := New().Load(filepath.Join(dir, "a.monke"))
+    importErr, ok := err.(*ImportError)
+    if !ok {
+        t.Fatalf("expected *ImportError, got %T %v", err, err)
+    }
+
+    expected := []string{
+        filepath.Join(dir, "b.monke"),
+        filepath.Join(dir, "c.monke"),
+        filepath.Join(dir, "b.monke"),
+    }
+    if strings.Join(importErr.Chain, " ") != strings.Join(expected, " ") {
+        t.Errorf("expected chain %v, got %v", expected, importErr.Chain)
+    }
+    if importErr.Pos.Filename != filepath.Join(dir, "c.monke") {
+        t.Errorf("expected the error in c.monke, got %s", importErr.Pos)
+    }
+    if !strings.HasPrefix(importErr.Msg, "import cycle: ") {
+        t.Errorf("unexpected message %q", importErr.Msg)
+    }
+}
+
+func TestModulesAreLoadedOnce(t *testing.T) {
+    dir := writeFiles(t, map[string]string{
+        "main.monke": `import "left.monke" as left; import "right.monke" as right;`,
+        "left.monke": `import "shared.monke" as shared;`,
+        "right.monke": `import "./shared.monke" as shared;`,
+        "shared.monke": `export let x = 1;`,
+    })
+
+    l := New()
+    module, err := l.Load(filepath.Join(dir, "main.monke"))
+    if err != nil {
+        t.Fatalf("unexpected error %v", err)
+    }
+
+    left := module.Imports["left"].Imports["shared"]
+    right := module.Imports["right"].Imports["shared"]
+    if left == nil || left != right {
+        t.Errorf("expected one shared module, got %p and %p", left, right)
+    }
+
+    again, err := l.Load(filepath.Join(dir, "shared.monke"))
+    if err != nil || again != left {
+        t.Errorf("expected the cached module, got %p (%v)", again, err)
+    }
+}
+
+func TestParseErrorInImport(t *testing.T) {
+    dir := writeFiles(t, map[string]string{
+        "main.monke": `import "broken.monke" as broken;`,
+        "broken.monke": `let x 1;`,
+    })
+
+    _, err := New().Load(filepath.Join(dir, "main.monke"))
+    parseErr, ok := err.(*ParseError)
+    if !ok {
+        t.Fatalf("expected *ParseError, got %T %v", err, err)
+    }
+    if parseErr.Path != filepath.Join(dir, "broken.monke") || len(parseErr.Errors) != 1 {
+        t.Errorf("expected one error in broken.monke, got %s: %v", parseErr.Path, parseErr.Errors.Strings())
+    }
+}
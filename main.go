@@ -2,12 +2,20 @@ package main
 
 import (
 	"fmt"
+	"monke/diagnostic"
+	"monke/loader"
 	"monke/repl"
+	"monke/types"
 	"os"
 	"os/user"
+	"path/filepath"
 )
 
 func main() {
+    if len(os.Args) > 1 {
+        os.Exit(runFile(os.Args[1]))
+    }
+
     user, err := user.Current()
     if err != nil {
         panic(err)
@@ -18,3 +26,81 @@ func main() {
 
     repl.Start(os.Stdin, os.Stdout)
 }
+
+// runFile parses a source file and the modules it imports, checks their
+// type annotations, and prints the program, or its errors. Imports not
+// found next to the importing file are looked up in the directories
+// listed in $MONKEPATH. It returns the process exit code.
+func runFile(path string) int {
+    l := loader.New(filepath.SplitList(os.Getenv("MONKEPATH"))...)
+    module, err := l.Load(path)
+    if err != nil {
+        reportLoadError(err)
+        return 1
+    }
+    if !checkTypes(module, map[*loader.Module]bool{}) {
+        return 1
+    }
+
+    fmt.Println(module.Program.String())
+    return 0
+}
+
+func reportLoadError(err error) {
+    renderer := diagnostic.Renderer{Color: diagnostic.IsTerminal(os.Stderr)}
+
+    switch err := err.(type) {
+    case *loader.ParseError:
+        // only read the source in full when there are snippets to show
+        source, readErr := os.ReadFile(err.Path)
+        if readErr != nil {
+            fmt.Fprintln(os.Stderr, readErr)
+            return
+        }
+        renderer.RenderErrors(os.Stderr, string(source), err.Errors)
+    case *loader.ImportError:
+        source, _ := os.ReadFile(err.Pos.Filename)
+        renderer.Render(os.Stderr, string(source), diagnostic.Diagnostic{
+            Severity: diagnostic.Error,
+            Code: "import",
+            Message: err.Msg,
+            Pos: err.Pos,
+            End: err.End,
+        })
+    default:
+        fmt.Fprintln(os.Stderr, err)
+    }
+}
+
+// checkTypes type checks module and everything it imports, once each,
+// and reports the errors. It returns whether there were none.
+func checkTypes(module *loader.Module, checked map[*loader.Module]bool) bool {
+    if checked[module] {
+        return true
+    }
+    checked[module] = true
+
+    ok := true
+    for _, imported := range module.Imports {
+        ok = checkTypes(imported, checked) && ok
+    }
+
+    errors := types.Check(module.Program)
+    if len(errors) == 0 {
+        return ok
+    }
+
+    renderer := diagnostic.Renderer{Color: diagnostic.IsTerminal(os.Stderr)}
+    source, _ := os.ReadFile(module.Path)
+    for _, err := range errors {
+        renderer.Render(os.Stderr, string(source), diagnostic.Diagnostic{
+            Severity: diagnostic.Error,
+            Code: "type",
+            Message: err.Msg,
+            Pos: err.Pos,
+            End: err.End,
+        })
+        fmt.Fprintln(os.Stderr)
+    }
+    return false
+}
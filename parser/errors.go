@@ -0,0 +1,185 @@
+package parser
+
+import (
+	"fmt"
+	"monke/ast"
+	"monke/token"
+	"sort"
+)
+
+type ErrorCode string
+
+const (
+    ErrIllegalToken      ErrorCode = "illegal-token"      // reported by the lexer
+    ErrUnexpectedToken   ErrorCode = "unexpected-token"   // Expected and Found are set
+    ErrNoPrefixParser    ErrorCode = "no-prefix-parser"   // token can not start an expression
+    ErrInvalidNumber     ErrorCode = "invalid-number"     // literal does not fit its type
+    ErrMissingExpression ErrorCode = "missing-expression"
+    ErrUnclosed          ErrorCode = "unclosed"           // block or hash without its '}'
+    ErrDuplicateName     ErrorCode = "duplicate-name"
+    ErrOutsideLoop       ErrorCode = "outside-loop"       // break or continue not in a loop
+    ErrInvalidTarget     ErrorCode = "invalid-target"     // assigning to something not a name or index
+    ErrUnreachable       ErrorCode = "unreachable"        // match arm after one matching everything
+    ErrNotTopLevel       ErrorCode = "not-top-level"      // import or export inside a block
+    ErrUnknownType       ErrorCode = "unknown-type"       // annotation naming no type
+    ErrTooManyErrors     ErrorCode = "too-many-errors"    // parsing stopped, see WithMaxErrors
+)
+
+// Error is a single problem found while parsing.
+type Error struct {
+    Pos token.Position
+    Code ErrorCode
+    Expected []token.TokenType // tokens that would have been accepted, if known
+    Found token.Token          // the offending token, zero for lexer errors
+    Msg string
+}
+
+func (e *Error) Error() string {
+    return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// ErrorList is a list of parse errors, in the order they were found
+// unless sorted.
+type ErrorList []*Error
+
+func (el ErrorList) Len() int { return len(el) }
+func (el ErrorList) Swap(i, j int) { el[i], el[j] = el[j], el[i] }
+
+func (el ErrorList) Less(i, j int) bool {
+    a, b := el[i].Pos, el[j].Pos
+    if a.Filename != b.Filename {
+        return a.Filename < b.Filename
+    }
+    if a.Offset != b.Offset {
+        return a.Offset < b.Offset
+    }
+    if el[i].Code != el[j].Code {
+        return el[i].Code < el[j].Code
+    }
+    return el[i].Msg < el[j].Msg
+}
+
+// Sort orders the list by file and position in the file.
+func (el ErrorList) Sort() {
+    sort.Stable(el)
+}
+
+// Dedup sorts the list and drops errors with the same position and
+// message as the one before.
+func (el *ErrorList) Dedup() {
+    el.Sort()
+
+    var last *Error
+    i := 0
+    for _, e := range *el {
+        if last == nil || e.Pos != last.Pos || e.Msg != last.Msg {
+            last = e
+            (*el)[i] = e
+            i++
+        }
+    }
+    *el = (*el)[:i]
+}
+
+// Filter returns the errors keep returns true for.
+func (el ErrorList) Filter(keep func(*Error) bool) ErrorList {
+    filtered := ErrorList{}
+    for _, e := range el {
+        if keep(e) {
+            filtered = append(filtered, e)
+        }
+    }
+    return filtered
+}
+
+// Strings returns the messages the way Parser.Errors reports them.
+func (el ErrorList) Strings() []string {
+    messages := []string{}
+    for _, e := range el {
+        messages = append(messages, e.Error())
+    }
+    return messages
+}
+
+func (el ErrorList) Error() string {
+    switch len(el) {
+    case 0:
+        return "no errors"
+    case 1:
+        return el[0].Error()
+    }
+    return fmt.Sprintf("%s (and %d more errors)", el[0], len(el) - 1)
+}
+
+// Err returns nil for an empty list, so it can be returned as an error.
+func (el ErrorList) Err() error {
+    if len(el) == 0 {
+        return nil
+    }
+    return el
+}
+
+func (p *Parser) addError(code ErrorCode, tok token.Token, format string, args ...any) *Error {
+    e := &Error{
+        Pos: tok.Pos,
+        Code: code,
+        Found: tok,
+        Msg: fmt.Sprintf(format, args...),
+    }
+    p.appendError(e)
+    return e
+}
+
+// appendError records e unless the error limit was reached. Going over
+// the limit is recorded once, as a last error saying so.
+func (p *Parser) appendError(e *Error) {
+    if p.tooManyErrors() {
+        return
+    }
+    if p.maxErrors > 0 && len(p.errors) == p.maxErrors {
+        e = &Error{Pos: e.Pos, Code: ErrTooManyErrors, Found: e.Found, Msg: "too many errors"}
+    }
+    p.errors = append(p.errors, e)
+}
+
+func (p *Parser) tooManyErrors() bool {
+    return p.maxErrors > 0 && len(p.errors) > p.maxErrors
+}
+
+// addUnexpected reports that tok is none of the expected token types.
+func (p *Parser) addUnexpected(tok token.Token, expected []token.TokenType, format string, args ...any) {
+    e := p.addError(ErrUnexpectedToken, tok, format, args...)
+    e.Expected = expected
+}
+
+// describe names a token for error messages
+func describe(tok token.Token) string {
+    switch tok.Type {
+    case token.EOF:
+        return "end of input"
+    case token.STRING:
+        return "string"
+    }
+    return fmt.Sprintf("'%s'", tok.Literal)
+}
+
+// describeExpression names the kind of an expression for error messages
+func describeExpression(e ast.Expression) string {
+    switch e.(type) {
+    case *ast.Integer, *ast.Float, *ast.StringLiteral, *ast.Boolean:
+        return "literal"
+    case *ast.ArrayLiteral, *ast.HashLiteral, *ast.FunctionLiteral:
+        return "literal"
+    case *ast.CallExpression:
+        return "function call"
+    case *ast.PrefixExpression, *ast.InfixExpression:
+        return "operator expression"
+    case *ast.AssignExpression:
+        return "assignment"
+    case *ast.IfExpression:
+        return "if expression"
+    case *ast.MatchExpression:
+        return "match expression"
+    }
+    return "expression"
+}
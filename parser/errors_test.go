@@ -0,0 +1,103 @@
+package parser
+
+import (
+	"monke/lexer"
+	"monke/token"
+	"testing"
+)
+
+func TestStructuredErrors(t *testing.T) {
+    input := `let x 5;
+if (x) { 1 } else 2
+let y = 99999999999999999999
+@`
+
+    p := New(lexer.New(input, lexer.WithFilename("broken.monke")))
+    p.ParseProgram()
+
+    errors := p.ErrorList()
+    errors.Sort()
+    expected := []struct {
+        line, column int
+        code ErrorCode
+        found token.TokenType
+        expected []token.TokenType
+    }{
+        {1, 7, ErrUnexpectedToken, token.INT, []token.TokenType{token.ASSIGN}},
+        {2, 19, ErrUnexpectedToken, token.INT, []token.TokenType{token.LBRACE, token.IF}},
+        {3, 9, ErrInvalidNumber, token.INT, nil},
+        {4, 1, ErrIllegalToken, "", nil},
+    }
+
+    if len(errors) != len(expected) {
+        t.Fatalf("expected %d errors, got %v", len(expected), errors.Strings())
+    }
+    for i, e := range expected {
+        actual := errors[i]
+        if actual.Pos.Filename != "broken.monke" || actual.Pos.Line != e.line || actual.Pos.Column != e.column {
+            t.Errorf("errors[%d] - expected position broken.monke:%d:%d, got %s", i, e.line, e.column, actual.Pos)
+        }
+        if actual.Code != e.code {
+            t.Errorf("errors[%d] - expected code %s, got %s", i, e.code, actual.Code)
+        }
+        if actual.Found.Type != e.found {
+            t.Errorf("errors[%d] - expected found %q, got %q", i, e.found, actual.Found.Type)
+        }
+        if len(actual.Expected) != len(e.expected) {
+            t.Fatalf("errors[%d] - expected %v, got %v", i, e.expected, actual.Expected)
+        }
+        for j := range e.expected {
+            if actual.Expected[j] != e.expected[j] {
+                t.Errorf("errors[%d] - expected %v, got %v", i, e.expected, actual.Expected)
+            }
+        }
+    }
+
+    messages := p.Errors()
+    if len(messages) != len(expected) || messages[0] != "broken.monke:1:7: expected next token '=', got 'INT'" {
+        t.Errorf("unexpected string view %q", messages[0])
+    }
+}
+
+func TestErrorListSortAndDedup(t *testing.T) {
+    at := func(file string, offset int) token.Position {
+        return token.Position{Filename: file, Offset: offset, Line: 1, Column: offset + 1}
+    }
+
+    errors := ErrorList{
+        {Pos: at("b.monke", 3), Code: ErrNoPrefixParser, Msg: "three"},
+        {Pos: at("a.monke", 9), Code: ErrUnclosed, Msg: "nine"},
+        {Pos: at("b.monke", 1), Code: ErrUnexpectedToken, Msg: "one"},
+        {Pos: at("a.monke", 9), Code: ErrUnclosed, Msg: "nine"},
+        {Pos: at("b.monke", 3), Code: ErrNoPrefixParser, Msg: "three"},
+    }
+
+    errors.Dedup()
+
+    expected := []string{
+        "a.monke:1:10: nine",
+        "b.monke:1:2: one",
+        "b.monke:1:4: three",
+    }
+    actual := errors.Strings()
+    if len(actual) != len(expected) {
+        t.Fatalf("expected %v, got %v", expected, actual)
+    }
+    for i := range expected {
+        if actual[i] != expected[i] {
+            t.Errorf("expected %q, got %q", expected[i], actual[i])
+        }
+    }
+
+    unclosed := errors.Filter(func(e *Error) bool { return e.Code == ErrUnclosed })
+    if len(unclosed) != 1 || unclosed[0].Msg != "nine" {
+        t.Errorf("expected only the unclosed error, got %v", unclosed.Strings())
+    }
+
+    if (ErrorList{}).Err() != nil {
+        t.Errorf("expected empty list to be a nil error")
+    }
+    if errors.Err() == nil || errors.Error() != "a.monke:1:10: nine (and 2 more errors)" {
+        t.Errorf("unexpected error %v", errors.Err())
+    }
+}
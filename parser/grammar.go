@@ -0,0 +1,67 @@
+package parser
+
+import (
+	"fmt"
+	"monke/token"
+)
+
+// Associativity decides how a chain of operators of the same priority
+// groups. Every infix operator has one, built-in ones included.
+type Associativity int
+
+const (
+    LeftAssoc  Associativity = iota // a - b - c is (a - b) - c
+    RightAssoc                      // a ** b ** c is a ** (b ** c)
+)
+
+// Operator is an entry of a parser's operator table. Added operators are
+// parsed into a PrefixExpression or InfixExpression with Symbol as its
+// operator.
+type Operator struct {
+    Symbol string
+    // Priority of an infix operator, which has to be above LOWEST to ever
+    // bind, or what the operand of a prefix one is parsed with; 0 means
+    // PREFIX there, like '-' and '!'.
+    Priority int
+    Associativity Associativity // infix only
+}
+
+// Grammar lists the operators a single parser adds to the built-in ones.
+// Giving a built-in operator changes its priority and associativity, but
+// not how it is parsed otherwise.
+type Grammar struct {
+    Prefix []Operator
+    Infix []Operator
+}
+
+// WithGrammar extends the parser, and its lexer, with the operators of g.
+// Other parsers are not affected. It panics on symbols the lexer can not
+// recognize, see lexer.AddOperator, and on infix operators with a
+// priority of LOWEST or less.
+//
+//     parser.New(l, parser.WithGrammar(parser.Grammar{
+//         Infix: []parser.Operator{{Symbol: "|>", Priority: parser.ASSIGN + 5}},
+//     }))
+func WithGrammar(g Grammar) Option {
+    return func(p *Parser) {
+        for _, op := range g.Prefix {
+            p.l.AddOperator(op.Symbol)
+            t := token.TokenType(op.Symbol)
+            if p.prefixParseFns[t] == nil {
+                p.registerPrefix(t, p.parsePrefixExpression)
+            }
+            p.prefixOperators[t] = op
+        }
+        for _, op := range g.Infix {
+            if op.Priority <= LOWEST {
+                panic(fmt.Sprintf("parser: infix operator %q has priority %d, it needs to be above LOWEST", op.Symbol, op.Priority))
+            }
+            p.l.AddOperator(op.Symbol)
+            t := token.TokenType(op.Symbol)
+            if p.infixParseFns[t] == nil {
+                p.registerInfix(t, p.parseInfixExpression)
+            }
+            p.infixOperators[t] = op
+        }
+    }
+}
@@ -0,0 +1,115 @@
+package parser
+
+import (
+	"monke/ast"
+	"monke/lexer"
+	"testing"
+)
+
+var pipeGrammar = Grammar{
+    Prefix: []Operator{
+        {Symbol: "not", Priority: OR + 5},
+        {Symbol: "#"},
+    },
+    Infix: []Operator{
+        {Symbol: "|>", Priority: ASSIGN + 5},
+        {Symbol: "<>", Priority: EQUALS},
+        {Symbol: "^", Priority: POWER, Associativity: RightAssoc},
+        {Symbol: "+", Priority: PRODUCT},
+    },
+}
+
+func TestGrammarOperators(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {"x |> f |> g", "((x |> f) |> g)"},
+        {"a + b |> f", "((a + b) |> f)"},
+        {"a || b |> f", "((a || b) |> f)"},
+        {"a <> b == c", "((a <> b) == c)"},
+        {"a ^ b ^ c", "(a ^ (b ^ c))"},
+        {"#a[0]", "(#(a[0]))"},
+        {"not a && b || c", "((not (a && b)) || c)"},
+        // '+' was moved up to PRODUCT
+        {"a * b + c", "((a * b) + c)"},
+        {"a + b * c", "((a + b) * c)"},
+    }
+
+    for _, test := range tests {
+        p := New(lexer.New(test.input), WithGrammar(pipeGrammar))
+        program := p.ParseProgram()
+        testParserErrors(t, p)
+
+        if program.String() != test.expected {
+            t.Errorf("%q - expected %q, got %q", test.input, test.expected, program.String())
+        }
+    }
+}
+
+func TestGrammarIsPerParser(t *testing.T) {
+    extended := New(lexer.New("a + b * c"), WithGrammar(pipeGrammar))
+    plain := New(lexer.New("a + b * c"))
+
+    if actual := extended.ParseProgram().String(); actual != "((a + b) * c)" {
+        t.Errorf("expected the grammar to apply, got %q", actual)
+    }
+    if actual := plain.ParseProgram().String(); actual != "(a + (b * c))" {
+        t.Errorf("expected built-in priorities, got %q", actual)
+    }
+
+    p := New(lexer.New("x |> f"))
+    p.ParseProgram()
+    if len(p.Errors()) == 0 {
+        t.Errorf("expected '|>' to be unknown without the grammar")
+    }
+}
+
+func TestGrammarInfixNode(t *testing.T) {
+    p := New(lexer.New("x |> f"), WithGrammar(pipeGrammar))
+    program := p.ParseProgram()
+    testParserErrors(t, p)
+
+    statement := program.Statements[0].(*ast.ExpressionStatement)
+    infix, ok := statement.Expression.(*ast.InfixExpression)
+    if !ok {
+        t.Fatalf("expected *ast.InfixExpression, got %T", statement.Expression)
+    }
+    if infix.Operator != "|>" || infix.Token.Type != "|>" {
+        t.Errorf("expected operator '|>', got %q of type %q", infix.Operator, infix.Token.Type)
+    }
+}
+
+func TestGrammarKeepsLongerBuiltins(t *testing.T) {
+    g := Grammar{Infix: []Operator{{Symbol: ".", Priority: INDEX}}}
+    p := New(lexer.New("let [a, ...r] = xs; a.b"), WithGrammar(g))
+    program := p.ParseProgram()
+    testParserErrors(t, p)
+
+    expected := "let [a, ...r] = xs;(a . b)"
+    if program.String() != expected {
+        t.Errorf("expected %q, got %q", expected, program.String())
+    }
+}
+
+func TestInvalidGrammar(t *testing.T) {
+    tests := []Grammar{
+        // would never bind
+        {Infix: []Operator{{Symbol: "|>"}}},
+        {Infix: []Operator{{Symbol: "|>", Priority: LOWEST}}},
+        // would be taken for the end of input or a return
+        {Infix: []Operator{{Symbol: "EOF", Priority: SUM}}},
+        {Prefix: []Operator{{Symbol: "RETURN"}}},
+    }
+
+    for _, g := range tests {
+        func() {
+            defer func() {
+                if recover() == nil {
+                    t.Errorf("expected WithGrammar(%v) to panic", g)
+                }
+            }()
+            New(lexer.New(""), WithGrammar(g))
+        }()
+    }
+}
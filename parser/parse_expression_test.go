@@ -48,6 +48,158 @@ func TestIntegerExpression(t *testing.T) {
     assertIntegerStatement(t, program.Statements[0], 555)
 }
 
+func TestNumberLiteralExpressions(t *testing.T) {
+    tests := []struct {
+        input string
+        expected interface{}
+    }{
+        {"0xff", int64(255)},
+        {"0o17", int64(15)},
+        {"0b1010", int64(10)},
+        {"1_000_000", int64(1000000)},
+        {"3.14", 3.14},
+        {"1e-9", 1e-9},
+        {"1_000.5", 1000.5},
+    }
+
+    for _, test := range tests {
+        l := lexer.New(test.input)
+        p := New(l)
+
+        program := p.ParseProgram()
+        testParserErrors(t, p)
+
+        if len(program.Statements) != 1 {
+            t.Fatalf("expected 1 statement, got %d", len(program.Statements))
+        }
+        s, ok := program.Statements[0].(*ast.ExpressionStatement)
+        if !ok {
+            t.Fatalf("expected ast.ExpressionStatement, got %T", program.Statements[0])
+        }
+
+        switch expected := test.expected.(type) {
+        case int64:
+            i, ok := s.Expression.(*ast.Integer)
+            if !ok {
+                t.Fatalf("expected ast.Integer, got %T", s.Expression)
+            }
+            if i.Value != expected {
+                t.Errorf("expected Value %d, got %d", expected, i.Value)
+            }
+        case float64:
+            f, ok := s.Expression.(*ast.Float)
+            if !ok {
+                t.Fatalf("expected ast.Float, got %T", s.Expression)
+            }
+            if f.Value != expected {
+                t.Errorf("expected Value %g, got %g", expected, f.Value)
+            }
+        }
+
+        if program.String() != test.input {
+            t.Errorf("expected %q to print back unchanged, got %q", test.input, program.String())
+        }
+    }
+}
+
+func TestMalformedNumberErrors(t *testing.T) {
+    l := lexer.New("1 + 0x")
+    p := New(l)
+    p.ParseProgram()
+
+    errors := p.Errors()
+    if len(errors) != 1 {
+        t.Fatalf("expected 1 error, got %v", errors)
+    }
+    expected := `1:5: invalid number literal "0x": hexadecimal literal has no digits`
+    if errors[0] != expected {
+        t.Errorf("expected error %q, got %q", expected, errors[0])
+    }
+}
+
+func TestBooleanExpression(t *testing.T) {
+    tests := []struct {
+        input string
+        expected bool
+    }{
+        {"true;", true},
+        {"false;", false},
+    }
+
+    for _, test := range tests {
+        l := lexer.New(test.input)
+        p := New(l)
+
+        program := p.ParseProgram()
+        testParserErrors(t, p)
+
+        if len(program.Statements) != 1 {
+            t.Fatalf("expected 1 statement, got %d", len(program.Statements))
+        }
+        s, ok := program.Statements[0].(*ast.ExpressionStatement)
+        if !ok {
+            t.Fatalf("expected ast.ExpressionStatement, got %T", program.Statements[0])
+        }
+        assertBooleanExpr(t, s.Expression, test.expected)
+    }
+}
+
+func TestStringExpression(t *testing.T) {
+    input := `"hello \"monke\"\n";`
+
+    l := lexer.New(input)
+    p := New(l)
+
+    program := p.ParseProgram()
+    testParserErrors(t, p)
+
+    if len(program.Statements) != 1 {
+        t.Fatalf("expected 1 statement, got %d", len(program.Statements))
+    }
+
+    s, ok := program.Statements[0].(*ast.ExpressionStatement)
+    if !ok {
+        t.Fatalf("expected ast.ExpressionStatement, got %T", program.Statements[0])
+    }
+
+    str, ok := s.Expression.(*ast.StringLiteral)
+    if !ok {
+        t.Fatalf("expected ast.StringLiteral, got %T", s.Expression)
+    }
+
+    if str.Value != "hello \"monke\"\n" {
+        t.Fatalf("expected string Value %q, got %q", "hello \"monke\"\n", str.Value)
+    }
+
+    if str.String() != input[:len(input) - 1] {
+        t.Fatalf("expected string to print as %s, got %s", input[:len(input) - 1], str.String())
+    }
+}
+
+func TestStringLexerErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {`"abc`, "1:1: unterminated string literal"},
+        {`x + "\q"`, "1:6: unknown escape sequence '\\q'"},
+    }
+
+    for _, test := range tests {
+        l := lexer.New(test.input)
+        p := New(l)
+        p.ParseProgram()
+
+        errors := p.Errors()
+        if len(errors) != 1 {
+            t.Fatalf("expected 1 error for %q, got %v", test.input, errors)
+        }
+        if errors[0] != test.expected {
+            t.Errorf("expected error %q, got %q", test.expected, errors[0])
+        }
+    }
+}
+
 type PrefixTest struct {
     input string
     operator string
@@ -92,6 +244,12 @@ func TestInfixExpression(t *testing.T) {
         {input: "420 != 69", leftVal: 420, operator: "!=", rightVal: 69},
         {input: "420 > 69", leftVal: 420, operator: ">", rightVal: 69},
         {input: "420 < 69", leftVal: 420, operator: "<", rightVal: 69},
+        {input: "420 <= 69", leftVal: 420, operator: "<=", rightVal: 69},
+        {input: "420 >= 69", leftVal: 420, operator: ">=", rightVal: 69},
+        {input: "420 % 69", leftVal: 420, operator: "%", rightVal: 69},
+        {input: "420 ** 69", leftVal: 420, operator: "**", rightVal: 69},
+        {input: "420 && 69", leftVal: 420, operator: "&&", rightVal: 69},
+        {input: "420 || 69", leftVal: 420, operator: "||", rightVal: 69},
     }
     
     for _, test := range infixTests {
@@ -126,6 +284,51 @@ func TestOperatorPrecedence(t *testing.T) {
         { "5 > 4 == 3 < 4", "((5 > 4) == (3 < 4))", },
         { "5 < 4 != 3 > 4", "((5 < 4) != (3 > 4))", },
         { "3 + 4 * 5 == 3 * 1 + 4 * 5", "((3 + (4 * 5)) == ((3 * 1) + (4 * 5)))", },
+        { "a % b * c", "((a % b) * c)", },
+        { "a + b % c", "(a + (b % c))", },
+        { "a <= b == c >= d", "((a <= b) == (c >= d))", },
+        { "a || b && c", "(a || (b && c))", },
+        { "a && b || c && d", "((a && b) || (c && d))", },
+        { "a == b && c != d", "((a == b) && (c != d))", },
+        { "a < b || c", "((a < b) || c)", },
+        { "a ** b ** c", "(a ** (b ** c))", },
+        { "a * b ** c", "(a * (b ** c))", },
+        { "a ** b * c", "((a ** b) * c)", },
+        { "-a ** b", "(-(a ** b))", },
+        { "a ** -b", "(a ** (-b))", },
+        { "x += y * 2", "(x += (y * 2))", },
+        { "x -= y += 1", "(x -= (y += 1))", },
+        { "x *= a || b", "(x *= (a || b))", },
+        { "x /= 2; y", "(x /= 2)y", },
+        { "true", "true", },
+        { "false", "false", },
+        { "3 > 5 == false", "((3 > 5) == false)", },
+        { "3 < 5 == true", "((3 < 5) == true)", },
+        { "!true != false", "((!true) != false)", },
+        { "1 + (2 + 3) + 4", "((1 + (2 + 3)) + 4)", },
+        { "(5 + 5) * 2", "((5 + 5) * 2)", },
+        { "2 / (5 + 5)", "(2 / (5 + 5))", },
+        { "-(5 + 5)", "(-(5 + 5))", },
+        { "!(true == true)", "(!(true == true))", },
+        { "(a ** b) ** c", "((a ** b) ** c)", },
+        { "(-a) ** b", "((-a) ** b)", },
+        { "(a || b) && c", "((a || b) && c)", },
+        { "((a))", "a", },
+        { "a + add(b * c) + d", "((a + add((b * c))) + d)", },
+        { "add(a, b, 1, 2 * 3, 4 + 5, add(6, 7 * 8))", "add(a, b, 1, (2 * 3), (4 + 5), add(6, (7 * 8)))", },
+        { "add(a + b + c * d / f + g)", "add((((a + b) + ((c * d) / f)) + g))", },
+        { "-f(x) ** 2", "(-(f(x) ** 2))", },
+        { "f(1)(2)", "f(1)(2)", },
+        { "f()(a, b)()", "f()(a, b)()", },
+        { "fn(x) { x }(5)", "fn(x) { x }(5)", },
+        { "a * [1, 2, 3, 4][b * c] * d", "((a * ([1, 2, 3, 4][(b * c)])) * d)", },
+        { "add(a * b[2], b[1], 2 * [1, 2][1])", "add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))", },
+        { "m[0][1]", "((m[0])[1])", },
+        { "f(x)[0]", "(f(x)[0])", },
+        { "fs[0](x)", "(fs[0])(x)", },
+        { "-a[0] ** 2", "(-((a[0]) ** 2))", },
+        { "[1, 2,]", "[1, 2]", },
+        { "f(a, b,)", "f(a, b)", },
     }
 
     for _, test := range tests {
@@ -142,6 +345,606 @@ func TestOperatorPrecedence(t *testing.T) {
         }
     }
 }
+
+func TestMixedAssociativity(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        { "a - b - c - d", "(((a - b) - c) - d)" },
+        { "a ** b ** c ** d", "(a ** (b ** (c ** d)))" },
+        { "a - b ** c ** d - e", "((a - (b ** (c ** d))) - e)" },
+        { "a ** b - c ** d - e", "(((a ** b) - (c ** d)) - e)" },
+        { "a / b ** c ** d / e", "((a / (b ** (c ** d))) / e)" },
+        { "x += y -= z - 1 - 2", "(x += (y -= ((z - 1) - 2)))" },
+        { "x *= a ** b ** c + d", "(x *= ((a ** (b ** c)) + d))" },
+        { "a && b && c || d || e", "((((a && b) && c) || d) || e)" },
+        // right associative operator sharing a priority with left ones
+        { "a - b <- c - d <- e", "((a - b) <- ((c - d) <- e))" },
+        { "a <- b + c", "(a <- (b + c))" },
+    }
+
+    grammar := Grammar{Infix: []Operator{{Symbol: "<-", Priority: SUM, Associativity: RightAssoc}}}
+    for _, test := range tests {
+        p := New(lexer.New(test.input), WithGrammar(grammar))
+        program := p.ParseProgram()
+        testParserErrors(t, p)
+
+        if actual := program.String(); actual != test.expected {
+            t.Errorf("%q - expected %q, got %q", test.input, test.expected, actual)
+        }
+    }
+}
+
+func TestAssignExpression(t *testing.T) {
+    tests := []struct {
+        input string
+        target string
+        operator string
+        value string
+    }{
+        {"x = 5", "x", "=", "5"},
+        {"arr[i + 1] = v", "(arr[(i + 1)])", "=", "v"},
+        {`h["k"] = [1]`, `(h["k"])`, "=", "[1]"},
+        {"x = y = 1", "x", "=", "(y = 1)"},
+        {"m[0][1] += 2 * 3", "((m[0])[1])", "+=", "(2 * 3)"},
+        {"x /= a || b", "x", "/=", "(a || b)"},
+    }
+
+    for _, test := range tests {
+        p := New(lexer.New(test.input))
+        program := p.ParseProgram()
+        testParserErrors(t, p)
+        assertStatementCount(t, program, 1)
+
+        statement := program.Statements[0].(*ast.ExpressionStatement)
+        assign, ok := statement.Expression.(*ast.AssignExpression)
+        if !ok {
+            t.Fatalf("%q - expected *ast.AssignExpression, got %T", test.input, statement.Expression)
+        }
+        if assign.Target.String() != test.target {
+            t.Errorf("%q - expected target %q, got %q", test.input, test.target, assign.Target.String())
+        }
+        if assign.Operator != test.operator {
+            t.Errorf("%q - expected operator %q, got %q", test.input, test.operator, assign.Operator)
+        }
+        if assign.Value.String() != test.value {
+            t.Errorf("%q - expected value %q, got %q", test.input, test.value, assign.Value.String())
+        }
+    }
+}
+
+func TestAssignTargetErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {"1 = 2", "1:1: cannot assign to literal '1', only to a name or an index expression"},
+        {"f() = 3", "1:1: cannot assign to function call 'f()', only to a name or an index expression"},
+        {"x + y = 1", "1:1: cannot assign to operator expression '(x + y)', only to a name or an index expression"},
+        {"(a + b) = 1", "1:2: cannot assign to operator expression '(a + b)', only to a name or an index expression"},
+        {"let z = -x *= 2", "1:9: cannot assign to operator expression '(-x)', only to a name or an index expression"},
+        {`s[0] = "t" = u`, `1:8: cannot assign to literal '"t"', only to a name or an index expression`},
+    }
+
+    for _, test := range tests {
+        p := New(lexer.New(test.input))
+        program := p.ParseProgram()
+
+        errors := p.Errors()
+        if len(errors) != 1 || errors[0] != test.expected {
+            t.Errorf("%q - expected error %q, got %v", test.input, test.expected, errors)
+        }
+        // the statement is kept, nothing after it is skipped
+        if len(program.Statements) != 1 {
+            t.Errorf("%q - expected 1 statement, got %d", test.input, len(program.Statements))
+        }
+    }
+}
+
+func TestOperatorRoundTrip(t *testing.T) {
+    inputs := []string{
+        "a + b * c ** d ** e % f",
+        "a || b && !c == d <= e",
+        "x += y -= z * 2",
+        "a[i] = b = c + 1",
+        "-a ** b >= c",
+        "(1 + 2) * 3 == !(true != false)",
+        "(a ** b) ** c",
+    }
+
+    for _, input := range inputs {
+        first := New(lexer.New(input)).ParseProgram().String()
+
+        p := New(lexer.New(first))
+        second := p.ParseProgram().String()
+        testParserErrors(t, p)
+
+        if first != second {
+            t.Errorf("printing %q does not round-trip: %q then %q", input, first, second)
+        }
+    }
+}
+
+func TestIfExpression(t *testing.T) {
+    input := `if (x < y) { x }`
+
+    p := New(lexer.New(input))
+    program := p.ParseProgram()
+    testParserErrors(t, p)
+
+    if len(program.Statements) != 1 {
+        t.Fatalf("expected 1 statement, got %d", len(program.Statements))
+    }
+    s, ok := program.Statements[0].(*ast.ExpressionStatement)
+    if !ok {
+        t.Fatalf("expected ast.ExpressionStatement, got %T", program.Statements[0])
+    }
+    expr, ok := s.Expression.(*ast.IfExpression)
+    if !ok {
+        t.Fatalf("expected ast.IfExpression, got %T", s.Expression)
+    }
+
+    if expr.Condition.String() != "(x < y)" {
+        t.Errorf("expected condition (x < y), got %s", expr.Condition.String())
+    }
+    if len(expr.Consequence.Statements) != 1 {
+        t.Fatalf("expected 1 consequence statement, got %d", len(expr.Consequence.Statements))
+    }
+    assertIdentifierExpr(t, expr.Consequence.Statements[0], "x")
+    if expr.Alternative != nil {
+        t.Errorf("expected no alternative, got %v", expr.Alternative)
+    }
+}
+
+func TestIfElseExpression(t *testing.T) {
+    input := `if (x < y) { x } else { let z = y; z }`
+
+    p := New(lexer.New(input))
+    program := p.ParseProgram()
+    testParserErrors(t, p)
+
+    if len(program.Statements) != 1 {
+        t.Fatalf("expected 1 statement, got %d", len(program.Statements))
+    }
+    expr, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.IfExpression)
+    if !ok {
+        t.Fatalf("expected ast.IfExpression, got %T", program.Statements[0])
+    }
+
+    alternative, ok := expr.Alternative.(*ast.BlockStatement)
+    if !ok {
+        t.Fatalf("expected alternative ast.BlockStatement, got %T", expr.Alternative)
+    }
+    if len(alternative.Statements) != 2 {
+        t.Fatalf("expected 2 alternative statements, got %d", len(alternative.Statements))
+    }
+    assertIdentifierExpr(t, alternative.Statements[1], "z")
+}
+
+func TestIfExpressionString(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        { "if (x) { 1 }", "if (x) { 1 }" },
+        { "if (x > 1) { 1; 2 } else { 3 }", "if (x > 1) { 1 2 } else { 3 }" },
+        { "if (a) {} else if (b) { 2 } else if (!c) { 3 } else { 4 }",
+          "if (a) {} else if (b) { 2 } else if (!c) { 3 } else { 4 }" },
+        { "let m = if (a) { return 1; } else { b };",
+          "let m = if (a) { return 1; } else { b };" },
+        { "if (if (a) { b }) { c }", "if (if (a) { b }) { c }" },
+    }
+
+    for _, test := range tests {
+        p := New(lexer.New(test.input))
+        program := p.ParseProgram()
+        testParserErrors(t, p)
+
+        actual := program.String()
+        if actual != test.expected {
+            t.Errorf("expected %q, got %q", test.expected, actual)
+        }
+
+        p = New(lexer.New(actual))
+        again := p.ParseProgram().String()
+        testParserErrors(t, p)
+        if again != actual {
+            t.Errorf("printing %q does not round-trip, got %q", actual, again)
+        }
+    }
+}
+
+func TestElseIfChain(t *testing.T) {
+    input := `if (a) { 1 } else if (b) { 2 } else { 3 }`
+
+    p := New(lexer.New(input))
+    program := p.ParseProgram()
+    testParserErrors(t, p)
+
+    first := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.IfExpression)
+    second, ok := first.Alternative.(*ast.IfExpression)
+    if !ok {
+        t.Fatalf("expected else if to be an ast.IfExpression, got %T", first.Alternative)
+    }
+    if second.Condition.String() != "b" {
+        t.Errorf("expected condition b, got %s", second.Condition.String())
+    }
+    if _, ok := second.Alternative.(*ast.BlockStatement); !ok {
+        t.Fatalf("expected final else to be an ast.BlockStatement, got %T", second.Alternative)
+    }
+}
+
+func TestIfExpressionErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {"if x { 1 }", "1:4: expected '(' after 'if', got 'x'"},
+        {"if () { 1 }", "1:5: missing condition in if expression"},
+        {"if (x { 1 }", "1:7: expected ')' after the if condition, got '{'"},
+        {"if (x) 1", "1:8: expected '{' after the if condition, got '1'"},
+        {"if (x) { 1 } else 2", "1:19: expected '{' or 'if' after 'else', got '2'"},
+        {"if (x) { 1 ", "1:12: expected '}' to close the block opened at 1:8"},
+        {"if (x) { 1 } else {\n 2", "2:3: expected '}' to close the block opened at 1:19"},
+    }
+
+    for _, test := range tests {
+        p := New(lexer.New(test.input))
+        p.ParseProgram()
+
+        errors := p.Errors()
+        if len(errors) == 0 || errors[0] != test.expected {
+            t.Errorf("expected first error %q, got %v", test.expected, errors)
+        }
+    }
+}
+
+func TestFunctionLiteral(t *testing.T) {
+    input := `fn(x, y) { x + y; }`
+
+    p := New(lexer.New(input))
+    program := p.ParseProgram()
+    testParserErrors(t, p)
+
+    if len(program.Statements) != 1 {
+        t.Fatalf("expected 1 statement, got %d", len(program.Statements))
+    }
+    s, ok := program.Statements[0].(*ast.ExpressionStatement)
+    if !ok {
+        t.Fatalf("expected ast.ExpressionStatement, got %T", program.Statements[0])
+    }
+    function, ok := s.Expression.(*ast.FunctionLiteral)
+    if !ok {
+        t.Fatalf("expected ast.FunctionLiteral, got %T", s.Expression)
+    }
+
+    if len(function.Parameters) != 2 {
+        t.Fatalf("expected 2 parameters, got %d", len(function.Parameters))
+    }
+    if function.Parameters[0].Value != "x" || function.Parameters[1].Value != "y" {
+        t.Errorf("expected parameters x, y, got %v", function.Parameters)
+    }
+    if len(function.Body.Statements) != 1 {
+        t.Fatalf("expected 1 body statement, got %d", len(function.Body.Statements))
+    }
+    if function.Body.Statements[0].String() != "(x + y)" {
+        t.Errorf("expected body (x + y), got %s", function.Body.Statements[0].String())
+    }
+}
+
+func TestFunctionParameters(t *testing.T) {
+    tests := []struct {
+        input string
+        expected []string
+    }{
+        {"fn() {};", []string{}},
+        {"fn(x) {};", []string{"x"}},
+        {"fn(x, y, z) {};", []string{"x", "y", "z"}},
+        {"fn(x,) {};", []string{"x"}},
+        {"fn(\n    x: int,\n    y: int,\n) {};", []string{"x", "y"}},
+    }
+
+    for _, test := range tests {
+        p := New(lexer.New(test.input))
+        program := p.ParseProgram()
+        testParserErrors(t, p)
+
+        function := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+        if len(function.Parameters) != len(test.expected) {
+            t.Fatalf("expected %d parameters, got %d", len(test.expected), len(function.Parameters))
+        }
+        for i, name := range test.expected {
+            if function.Parameters[i].Value != name {
+                t.Errorf("expected parameter %s, got %s", name, function.Parameters[i].Value)
+            }
+        }
+    }
+}
+
+func TestCallExpression(t *testing.T) {
+    input := "add(1, 2 * 3, 4 + 5);"
+
+    p := New(lexer.New(input))
+    program := p.ParseProgram()
+    testParserErrors(t, p)
+
+    if len(program.Statements) != 1 {
+        t.Fatalf("expected 1 statement, got %d", len(program.Statements))
+    }
+    call, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+    if !ok {
+        t.Fatalf("expected ast.CallExpression, got %T", program.Statements[0])
+    }
+
+    if call.Function.String() != "add" {
+        t.Errorf("expected function add, got %s", call.Function.String())
+    }
+    if len(call.Arguments) != 3 {
+        t.Fatalf("expected 3 arguments, got %d", len(call.Arguments))
+    }
+    assertIntegerExpr(t, call.Arguments[0], 1)
+    if call.Arguments[1].String() != "(2 * 3)" || call.Arguments[2].String() != "(4 + 5)" {
+        t.Errorf("unexpected arguments %v", call.Arguments)
+    }
+}
+
+func TestChainedCallExpression(t *testing.T) {
+    p := New(lexer.New("f(1)(2)"))
+    program := p.ParseProgram()
+    testParserErrors(t, p)
+
+    outer := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+    inner, ok := outer.Function.(*ast.CallExpression)
+    if !ok {
+        t.Fatalf("expected callee to be ast.CallExpression, got %T", outer.Function)
+    }
+    assertIntegerExpr(t, outer.Arguments[0], 2)
+    assertIntegerExpr(t, inner.Arguments[0], 1)
+}
+
+func TestSourceProgram(t *testing.T) {
+    input := `
+        let five = 5;
+        let ten = 10;
+        let add = fn(x, y) {
+        x + y;
+        };
+        let result = add(five, ten);`
+
+    p := New(lexer.New(input))
+    program := p.ParseProgram()
+    testParserErrors(t, p)
+
+    expected := "let five = 5;let ten = 10;let add = fn(x, y) { (x + y) };let result = add(five, ten);"
+    if program.String() != expected {
+        t.Errorf("expected %q, got %q", expected, program.String())
+    }
+}
+
+func TestFunctionErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {"fn(x, y, x) { x }", "1:10: duplicate parameter name 'x'"},
+        {"fn x { x }", "1:4: expected '(' after 'fn', got 'x'"},
+        {"fn(x) x", "1:7: expected '{' to open the function body, got 'x'"},
+        {"fn(x, 1) { x }", "1:7: expected next token 'IDENT', got 'INT'"},
+        {"add(1, 2", "1:9: expected next token ')', got 'EOF'"},
+    }
+
+    for _, test := range tests {
+        p := New(lexer.New(test.input))
+        p.ParseProgram()
+
+        errors := p.Errors()
+        if len(errors) == 0 || errors[0] != test.expected {
+            t.Errorf("expected first error %q, got %v", test.expected, errors)
+        }
+    }
+}
+
+func TestArrayLiteral(t *testing.T) {
+    tests := []struct {
+        input string
+        expected []string
+    }{
+        {"[]", []string{}},
+        {"[1, 2 * 2, 3 + 3]", []string{"1", "(2 * 2)", "(3 + 3)"}},
+        {"[1, [2, 3],]", []string{"1", "[2, 3]"}},
+        {"[\n  \"a\",\n  \"b\",\n]", []string{`"a"`, `"b"`}},
+    }
+
+    for _, test := range tests {
+        p := New(lexer.New(test.input))
+        program := p.ParseProgram()
+        testParserErrors(t, p)
+
+        if len(program.Statements) != 1 {
+            t.Fatalf("expected 1 statement, got %d", len(program.Statements))
+        }
+        array, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.ArrayLiteral)
+        if !ok {
+            t.Fatalf("expected ast.ArrayLiteral, got %T", program.Statements[0])
+        }
+        if len(array.Elements) != len(test.expected) {
+            t.Fatalf("expected %d elements, got %d", len(test.expected), len(array.Elements))
+        }
+        for i, expected := range test.expected {
+            if array.Elements[i].String() != expected {
+                t.Errorf("expected element %s, got %s", expected, array.Elements[i].String())
+            }
+        }
+    }
+}
+
+func TestIndexExpression(t *testing.T) {
+    p := New(lexer.New("m[0][i + 1]"))
+    program := p.ParseProgram()
+    testParserErrors(t, p)
+
+    outer, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.IndexExpression)
+    if !ok {
+        t.Fatalf("expected ast.IndexExpression, got %T", program.Statements[0])
+    }
+    if outer.Index.String() != "(i + 1)" {
+        t.Errorf("expected index (i + 1), got %s", outer.Index.String())
+    }
+
+    inner, ok := outer.Left.(*ast.IndexExpression)
+    if !ok {
+        t.Fatalf("expected nested ast.IndexExpression, got %T", outer.Left)
+    }
+    assertIntegerExpr(t, inner.Index, 0)
+    if inner.Left.String() != "m" {
+        t.Errorf("expected m, got %s", inner.Left.String())
+    }
+}
+
+func TestArrayErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {"a[]", "1:3: missing index expression"},
+        {"a[1", "1:4: expected next token ']', got 'EOF'"},
+        {"[1, 2", "1:6: expected next token ']', got 'EOF'"},
+        {"[1,,]", "1:4: No prefix parser for token ','"},
+        {"[,]", "1:2: No prefix parser for token ','"},
+    }
+
+    for _, test := range tests {
+        p := New(lexer.New(test.input))
+        p.ParseProgram()
+
+        errors := p.Errors()
+        if len(errors) == 0 || errors[0] != test.expected {
+            t.Errorf("expected first error %q, got %v", test.expected, errors)
+        }
+    }
+}
+
+func TestHashLiteral(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+        pairs int
+    }{
+        {"{}", "{}", 0},
+        {`{"name": "x", 1: true}`, `{"name": "x", 1: true}`, 2},
+        {`{"b": 1, "a": 2, "c": 3,}`, `{"b": 1, "a": 2, "c": 3}`, 3},
+        {`{1 + 1: a * b, f(x): {"nested": [1]}}`, `{(1 + 1): (a * b), f(x): {"nested": [1]}}`, 2},
+        {`let h = {true: fn(x) { x }}`, `let h = {true: fn(x) { x }};`, 1},
+        {`if (x) { {"a": 1} }`, `if (x) { {"a": 1} }`, 0},
+    }
+
+    for _, test := range tests {
+        p := New(lexer.New(test.input))
+        program := p.ParseProgram()
+        testParserErrors(t, p)
+
+        if program.String() != test.expected {
+            t.Errorf("expected %q, got %q", test.expected, program.String())
+        }
+
+        s, ok := program.Statements[0].(*ast.ExpressionStatement)
+        if !ok {
+            continue
+        }
+        if hash, ok := s.Expression.(*ast.HashLiteral); ok && len(hash.Pairs) != test.pairs {
+            t.Errorf("expected %d pairs, got %d", test.pairs, len(hash.Pairs))
+        }
+    }
+}
+
+func TestHashLiteralKeepsOrder(t *testing.T) {
+    p := New(lexer.New(`{"z": 1, "y": 2, "x": 3}`))
+    program := p.ParseProgram()
+    testParserErrors(t, p)
+
+    hash := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.HashLiteral)
+    expectedKeys := []string{"z", "y", "x"}
+    for i, key := range expectedKeys {
+        str, ok := hash.Pairs[i].Key.(*ast.StringLiteral)
+        if !ok {
+            t.Fatalf("expected ast.StringLiteral key, got %T", hash.Pairs[i].Key)
+        }
+        if str.Value != key {
+            t.Errorf("expected key %d to be %s, got %s", i, key, str.Value)
+        }
+        assertIntegerExpr(t, hash.Pairs[i].Value, int64(i + 1))
+    }
+}
+
+func TestHashLiteralErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {`{"name" "x"}`, "1:9: expected ':' after hash key, got string"},
+        {`{"a": 1 "b": 2}`, "1:9: expected ',' or '}' after hash value, got string"},
+        {`{x; y}`, "1:3: expected ':' after hash key, got ';' (blocks can only follow if, else, fn or loops)"},
+        {`{x}`, "1:3: expected ':' after hash key, got '}' (blocks can only follow if, else, fn or loops)"},
+        {`{ let x = 1; }`, "1:3: expected a hash key, got 'let' (blocks can only follow if, else, fn or loops)"},
+        {`{"a": }`, "1:7: No prefix parser for token '}'"},
+        {`{"a": 1,`, "1:9: expected '}' to close the hash opened at 1:1"},
+    }
+
+    for _, test := range tests {
+        p := New(lexer.New(test.input))
+        p.ParseProgram()
+
+        errors := p.Errors()
+        if len(errors) != 1 || errors[0] != test.expected {
+            t.Errorf("expected only error %q, got %v", test.expected, errors)
+        }
+    }
+}
+
+func TestHashLiteralErrorInFunction(t *testing.T) {
+    input := `let f = fn(x) {
+        let h = {"a" 1};
+        let y = x + 1;
+        y
+    };
+    f(1)`
+
+    p := New(lexer.New(input))
+    program := p.ParseProgram()
+
+    expectedErrors := []string{`2:22: expected ':' after hash key, got '1'`}
+    if !reflect.DeepEqual(p.Errors(), expectedErrors) {
+        t.Errorf("expected errors %v, got %v", expectedErrors, p.Errors())
+    }
+    expected := "let f = fn(x) { let y = (x + 1); y };f(1)"
+    if program.String() != expected {
+        t.Errorf("expected %q, got %q", expected, program.String())
+    }
+}
+
+func TestGroupedExpressionErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {"(1 + 2", "1:7: expected next token ')', got 'EOF'"},
+        {"(1 + 2;", "1:7: expected next token ')', got ';'"},
+        {"()", "1:2: No prefix parser for token ')'"},
+        {"(*)", "1:2: No prefix parser for token '*'"},
+    }
+
+    for _, test := range tests {
+        p := New(lexer.New(test.input))
+        p.ParseProgram()
+
+        errors := p.Errors()
+        if len(errors) != 1 || errors[0] != test.expected {
+            t.Errorf("expected only error %q, got %v", test.expected, errors)
+        }
+    }
+}
+
 func assertIsExpressionType(t *testing.T, statement ast.Statement, expected ast.Expression) {
     s, ok := statement.(*ast.ExpressionStatement)
     if !ok {
@@ -201,6 +1004,22 @@ func assertIntegerExpr(t *testing.T, expr ast.Expression, expectedValue int64) {
     }
 }
 
+func assertBooleanExpr(t *testing.T, expr ast.Expression, expectedValue bool) {
+    b, ok := expr.(*ast.Boolean)
+    if !ok {
+        t.Fatalf("expected ast.Boolean, got %T", expr)
+    }
+
+    if b.Value != expectedValue {
+        t.Fatalf("expected Value %t, got %t", expectedValue, b.Value)
+    }
+
+    expectedLiteral := strconv.FormatBool(expectedValue)
+    if b.TokenLiteral() != expectedLiteral {
+        t.Fatalf("expected literal %s, got %s", expectedLiteral, b.TokenLiteral())
+    }
+}
+
 func assertPrefixExpr(t *testing.T, statement ast.Statement, test PrefixTest) {
     s, ok := statement.(*ast.ExpressionStatement)
     if !ok {
@@ -1,33 +1,54 @@
 package parser
 
 import (
-	"fmt"
 	"monke/ast"
+	"monke/debug"
 	"monke/lexer"
 	"monke/token"
 	"strconv"
 )
 
+// Priorities are spaced out, so that operators added with WithGrammar can
+// go between two levels, like SUM + 5.
 const (
-    _ int = iota
+    _ int = iota * 10
     LOWEST
+    ASSIGN      // x = y, x += y
+    OR          // x || y
+    AND         // x && y
     EQUALS
     LESSGREATER
     SUM
     PRODUCT
     PREFIX
+    POWER       // x ** y, binds tighter than prefix so -x ** 2 is -(x ** 2)
     CALL
+    INDEX
 )
 
-var priorities = map[token.TokenType]int {
-    token.EQ: EQUALS,
-    token.NEQ: EQUALS,
-    token.LT: LESSGREATER,
-    token.GT: LESSGREATER,
-    token.PLUS: SUM,
-    token.MINUS: SUM,
-    token.SLASH: PRODUCT,
-    token.ASTERISK: PRODUCT,
+// the built-in infix operators, each parser starts with a copy
+var defaultInfixOperators = []Operator{
+    {Symbol: token.ASSIGN, Priority: ASSIGN, Associativity: RightAssoc},
+    {Symbol: token.PLUS_ASSIGN, Priority: ASSIGN, Associativity: RightAssoc},
+    {Symbol: token.MINUS_ASSIGN, Priority: ASSIGN, Associativity: RightAssoc},
+    {Symbol: token.ASTERISK_ASSIGN, Priority: ASSIGN, Associativity: RightAssoc},
+    {Symbol: token.SLASH_ASSIGN, Priority: ASSIGN, Associativity: RightAssoc},
+    {Symbol: token.OR, Priority: OR},
+    {Symbol: token.AND, Priority: AND},
+    {Symbol: token.EQ, Priority: EQUALS},
+    {Symbol: token.NEQ, Priority: EQUALS},
+    {Symbol: token.LT, Priority: LESSGREATER},
+    {Symbol: token.GT, Priority: LESSGREATER},
+    {Symbol: token.LTE, Priority: LESSGREATER},
+    {Symbol: token.GTE, Priority: LESSGREATER},
+    {Symbol: token.PLUS, Priority: SUM},
+    {Symbol: token.MINUS, Priority: SUM},
+    {Symbol: token.SLASH, Priority: PRODUCT},
+    {Symbol: token.ASTERISK, Priority: PRODUCT},
+    {Symbol: token.PERCENT, Priority: PRODUCT},
+    {Symbol: token.POWER, Priority: POWER, Associativity: RightAssoc},
+    {Symbol: token.LPAREN, Priority: CALL},
+    {Symbol: token.LBRACKET, Priority: INDEX},
 }
 
 type (
@@ -37,23 +58,75 @@ type (
 
 type Parser struct {
     l *lexer.Lexer
-    errors []string
+    errors ErrorList
+    lexerErrors int // lexer errors already copied to errors
 
     curToken token.Token
     peekToken token.Token
+    comments []token.Token
 
     prefixParseFns map[token.TokenType] prefixParseFn
     infixParseFns map[token.TokenType] infixParseFn
+    infixOperators map[token.TokenType]Operator
+    prefixOperators map[token.TokenType]Operator // only the added ones
+
+    maxErrors int
+    tracer *debug.Tracer
+
+    // loops around the current statement, within the innermost function
+    loopDepth int
+    // braces opened up to curToken and not closed yet
+    braceDepth int
+    // blocks around the current statement, 0 at the top level
+    blockDepth int
 }
 
-func New(l *lexer.Lexer) *Parser {
-    p := &Parser{l: l, errors: []string{}}
-    p.nextToken()
-    p.nextToken()
+type Option func(*Parser)
+
+// WithMaxErrors stops parsing after n errors, adding a last one saying
+// that there were too many. 0 means no limit, the default is 10.
+func WithMaxErrors(n int) Option {
+    return func(p *Parser) {
+        p.maxErrors = n
+    }
+}
+
+// tokens a statement can start with, where parsing picks up after an error
+var statementStarts = map[token.TokenType]bool {
+    token.LET: true,
+    token.RETURN: true,
+    token.FUNCTION: true,
+    token.WHILE: true,
+    token.FOR: true,
+    token.BREAK: true,
+    token.CONTINUE: true,
+    token.IMPORT: true,
+    token.EXPORT: true,
+}
+
+func New(l *lexer.Lexer, opts ...Option) *Parser {
+    p := &Parser{l: l, errors: ErrorList{}, maxErrors: 10}
+
+    p.infixOperators = make(map[token.TokenType]Operator)
+    for _, op := range defaultInfixOperators {
+        p.infixOperators[token.TokenType(op.Symbol)] = op
+    }
+    p.prefixOperators = make(map[token.TokenType]Operator)
 
     p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
     p.registerPrefix(token.IDENT, p.parseIndentifier)
     p.registerPrefix(token.INT, p.parseInteger)
+    p.registerPrefix(token.FLOAT, p.parseFloat)
+    p.registerPrefix(token.STRING, p.parseStringLiteral)
+    p.registerPrefix(token.TRUE, p.parseBoolean)
+    p.registerPrefix(token.FALSE, p.parseBoolean)
+    p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
+    p.registerPrefix(token.IF, p.parseIfExpression)
+    p.registerPrefix(token.MATCH, p.parseMatchExpression)
+    p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+    p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
+    p.registerPrefix(token.LBRACE, p.parseHashLiteral)
+    p.registerPrefix(token.ILLEGAL, p.parseIllegal)
     p.registerPrefix(token.BANG, p.parsePrefixExpression)
     p.registerPrefix(token.MINUS, p.parsePrefixExpression)
     
@@ -66,31 +139,120 @@ func New(l *lexer.Lexer) *Parser {
     p.registerInfix(token.NEQ, p.parseInfixExpression)
     p.registerInfix(token.LT, p.parseInfixExpression)
     p.registerInfix(token.GT, p.parseInfixExpression)
+    p.registerInfix(token.LTE, p.parseInfixExpression)
+    p.registerInfix(token.GTE, p.parseInfixExpression)
+    p.registerInfix(token.PERCENT, p.parseInfixExpression)
+    p.registerInfix(token.POWER, p.parseInfixExpression)
+    p.registerInfix(token.AND, p.parseInfixExpression)
+    p.registerInfix(token.OR, p.parseInfixExpression)
+    p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+    p.registerInfix(token.PLUS_ASSIGN, p.parseAssignExpression)
+    p.registerInfix(token.MINUS_ASSIGN, p.parseAssignExpression)
+    p.registerInfix(token.ASTERISK_ASSIGN, p.parseAssignExpression)
+    p.registerInfix(token.SLASH_ASSIGN, p.parseAssignExpression)
+    p.registerInfix(token.LPAREN, p.parseCallExpression)
+    p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+
+    for _, opt := range opts {
+        opt(p)
+    }
+    p.nextToken()
+    p.nextToken()
     return p
 }
 
+// Errors returns the error messages, formatted as "line:col: message".
 func (p *Parser) Errors() []string {
-    return p.errors
+    return p.errors.Strings()
+}
+
+// ErrorList returns a copy of the errors with their position, code and
+// tokens. Lexer errors can show up before the parse errors preceding them,
+// since the lexer runs a token ahead; Sort fixes the order.
+func (p *Parser) ErrorList() ErrorList {
+    return append(ErrorList{}, p.errors...)
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
     program := &ast.Program{}
     program.Statements = []ast.Statement{}
 
-    for !p.isCurToken(token.EOF) {
+    for !p.isCurToken(token.EOF) && !p.tooManyErrors() {
+        depth := p.outerDepth()
         statement := p.parseStatement()
         if statement != nil {
             program.Statements = append(program.Statements, statement)
+        } else {
+            p.synchronize(depth)
         }
         p.nextToken()
     }
+    program.Comments = p.comments
 
     return program
 }
 
+// synchronize skips the rest of a broken statement, so that one mistake
+// does not turn into a cascade of errors. depth is the brace depth the
+// statement started at, see outerDepth. It stops on a ';', or before a
+// '}' or a token starting a new statement, once the braces the statement
+// opened are closed. curToken is left on the last skipped token, like
+// after a statement.
+func (p *Parser) synchronize(depth int) {
+    for !p.isCurToken(token.EOF) && !p.isPeekToken(token.EOF) {
+        if p.braceDepth <= depth {
+            if p.isCurToken(token.SEMICOLON) {
+                return
+            }
+            if p.isPeekToken(token.RBRACE) || statementStarts[p.peekToken.Type] {
+                return
+            }
+        }
+        p.nextToken()
+    }
+}
+
+// outerDepth returns the brace depth before curToken, which nextToken
+// has already counted if it is a brace.
+func (p *Parser) outerDepth() int {
+    switch p.curToken.Type {
+    case token.LBRACE:
+        return p.braceDepth - 1
+    case token.RBRACE:
+        return p.braceDepth + 1
+    }
+    return p.braceDepth
+}
+
+// failedOnCurToken reports whether the last error is about curToken,
+// which means the broken statement did not get to consume it.
+func (p *Parser) failedOnCurToken() bool {
+    if len(p.errors) == 0 {
+        return false
+    }
+    last := p.errors[len(p.errors) - 1]
+    return last.Found.Type == p.curToken.Type && last.Found.Pos == p.curToken.Pos
+}
+
 func (p *Parser) nextToken() {
     p.curToken = p.peekToken
+    switch p.curToken.Type {
+    case token.LBRACE:
+        p.braceDepth += 1
+    case token.RBRACE:
+        p.braceDepth -= 1
+    }
     p.peekToken = p.l.NextToken()
+    for p.peekToken.Type == token.COMMENT {
+        p.comments = append(p.comments, p.peekToken)
+        p.peekToken = p.l.NextToken()
+    }
+
+    lexerErrors := p.l.Errors()
+    for _, e := range lexerErrors[p.lexerErrors:] {
+        p.appendError(&Error{Pos: e.Pos, Code: ErrIllegalToken, Msg: e.Msg})
+    }
+    p.lexerErrors = len(lexerErrors)
 }
 
 func (p *Parser) registerPrefix(tokenType token.TokenType, fn prefixParseFn) {
@@ -102,11 +264,22 @@ func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
 }
 
 func (p *Parser) parseStatement() ast.Statement {
+    defer p.untrace(p.trace("parseStatement"))
     switch p.curToken.Type {
     case token.LET:
         return p.parseLetStatement()
     case token.RETURN:
         return p.parseReturnStatement()
+    case token.WHILE:
+        return p.parseWhileStatement()
+    case token.FOR:
+        return p.parseForStatement()
+    case token.BREAK, token.CONTINUE:
+        return p.parseLoopControl()
+    case token.IMPORT:
+        return p.parseImportStatement()
+    case token.EXPORT:
+        return p.parseExportStatement()
     default:
         return p.parseExpressionStatement()
     }
@@ -122,14 +295,27 @@ func (p *Parser) nextIfPeek(t token.TokenType) bool {
     }
 }
 
+// expectPeek is nextIfPeek with an error describing what was expected,
+// like "expected ')' after the while condition, got '{'".
+func (p *Parser) expectPeek(t token.TokenType, what string) bool {
+    if p.isPeekToken(t) {
+        p.nextToken()
+        return true
+    }
+    p.addUnexpected(
+        p.peekToken, []token.TokenType{t},
+        "expected %s, got %s", what, describe(p.peekToken))
+    return false
+}
+
 func (p *Parser) addPeekError(t token.TokenType) {
-    e := fmt.Sprintf("expected next token '%s', got '%s'", t, p.peekToken.Type)
-    p.errors = append(p.errors, e)
+    p.addUnexpected(
+        p.peekToken, []token.TokenType{t},
+        "expected next token '%s', got '%s'", t, p.peekToken.Type)
 }
 
 func (p *Parser) addNoPrefixParseFnError(t token.TokenType) {
-    e := fmt.Sprintf("No prefix parser for token '%s'", t)
-    p.errors = append(p.errors, e)
+    p.addError(ErrNoPrefixParser, p.curToken, "No prefix parser for token '%s'", t)
 }
 
 func (p *Parser) isPeekToken(t token.TokenType) bool {
@@ -141,114 +327,357 @@ func (p *Parser) isCurToken(t token.TokenType) bool {
 }
 
 func (p *Parser) peekPriority() int {
-    if pr, ok := priorities[p.peekToken.Type]; ok {
-        return pr
+    if op, ok := p.infixOperators[p.peekToken.Type]; ok {
+        return op.Priority
     }
     return LOWEST
 }
 
-func (p *Parser) curPriority() int {
-    if pr, ok := priorities[p.curToken.Type]; ok {
-        return pr
-    }
-    return LOWEST
-}
-
-func (p *Parser) parseLetStatement() *ast.LetStatement {
+func (p *Parser) parseLetStatement() ast.Statement {
+    defer p.untrace(p.trace("parseLetStatement"))
     letStatement := &ast.LetStatement{Token: p.curToken}
 
-    if !p.nextIfPeek(token.IDENT) {
-        return nil
+    if p.isPeekToken(token.LBRACKET) || p.isPeekToken(token.LBRACE) {
+        p.nextToken()
+        letStatement.Pattern = p.parsePattern()
+        if letStatement.Pattern == nil {
+            return nil
+        }
+        p.checkBindings(letStatement.Pattern)
+        p.checkIrrefutable(letStatement.Pattern)
+    } else {
+        if !p.nextIfPeek(token.IDENT) {
+            return nil
+        }
+        letStatement.Name = &ast.Identifier{
+            Token: p.curToken,
+            Value: p.curToken.Literal,
+        }
     }
 
-    letStatement.Name = &ast.Identifier{
-        Token: p.curToken,
-        Value: p.curToken.Literal,
+    annotation, ok := p.parseTypeAnnotation()
+    if !ok {
+        return nil
     }
+    letStatement.Type = annotation
 
     if !p.nextIfPeek(token.ASSIGN) {
         return nil
     }
 
-    // parse expression
-    for !p.isCurToken(token.SEMICOLON) {
-        p.nextToken()
+    p.nextToken()
+    letStatement.Value = p.parseExpression(LOWEST)
+    if letStatement.Value == nil {
+        return nil
     }
 
+    p.skipSemicolons()
     return letStatement
 }
 
-func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
+func (p *Parser) parseReturnStatement() ast.Statement {
+    defer p.untrace(p.trace("parseReturnStatement"))
     statement := &ast.ReturnStatement{Token: p.curToken}
 
-    // parse expression
+    // bare return, value stays nil
+    if p.isPeekToken(token.SEMICOLON) || p.isPeekToken(token.RBRACE) || p.isPeekToken(token.EOF) {
+        p.skipSemicolons()
+        return statement
+    }
+
     p.nextToken()
-    for !p.isCurToken(token.SEMICOLON) {
-        p.nextToken()
+    statement.Value = p.parseExpression(LOWEST)
+    if statement.Value == nil {
+        return nil
+    }
+
+    p.skipSemicolons()
+    return statement
+}
+
+func (p *Parser) parseWhileStatement() ast.Statement {
+    defer p.untrace(p.trace("parseWhileStatement"))
+    statement := &ast.WhileStatement{Token: p.curToken}
+
+    if !p.expectPeek(token.LPAREN, "'(' after 'while'") {
+        return nil
+    }
+    if p.isPeekToken(token.RPAREN) {
+        p.addError(ErrMissingExpression, p.peekToken, "missing condition in while loop")
+        return nil
+    }
+    p.nextToken()
+
+    statement.Condition = p.parseExpression(LOWEST)
+    if statement.Condition == nil {
+        return nil
+    }
+    if !p.expectPeek(token.RPAREN, "')' after the while condition") {
+        return nil
+    }
+
+    statement.Body = p.parseLoopBody()
+    if statement.Body == nil {
+        return nil
+    }
+    return statement
+}
+
+// parseForStatement parses for (x in xs) { ... }
+func (p *Parser) parseForStatement() ast.Statement {
+    defer p.untrace(p.trace("parseForStatement"))
+    statement := &ast.ForStatement{Token: p.curToken}
+
+    if !p.expectPeek(token.LPAREN, "'(' after 'for'") {
+        return nil
+    }
+    if !p.expectPeek(token.IDENT, "a loop variable name") {
+        return nil
+    }
+    statement.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+    if !p.expectPeek(token.IN, "'in' after the loop variable") {
+        return nil
+    }
+    p.nextToken()
+
+    statement.Iterable = p.parseExpression(LOWEST)
+    if statement.Iterable == nil {
+        return nil
+    }
+    if !p.expectPeek(token.RPAREN, "')' after the iterated expression") {
+        return nil
+    }
+
+    statement.Body = p.parseLoopBody()
+    if statement.Body == nil {
+        return nil
+    }
+    return statement
+}
+
+// parseLoopBody parses the block of a loop, starting before its '{'.
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+    if !p.expectPeek(token.LBRACE, "'{' to open the loop body") {
+        return nil
     }
 
+    p.loopDepth += 1
+    defer func() { p.loopDepth -= 1 }()
+    return p.parseBlockStatement()
+}
+
+// parseLoopControl parses break and continue, which are kept even when
+// misplaced, after reporting it.
+func (p *Parser) parseLoopControl() ast.Statement {
+    var statement ast.Statement
+    if p.isCurToken(token.BREAK) {
+        statement = &ast.BreakStatement{Token: p.curToken}
+    } else {
+        statement = &ast.ContinueStatement{Token: p.curToken}
+    }
+
+    if p.loopDepth == 0 {
+        p.addError(ErrOutsideLoop, p.curToken, "'%s' outside of a loop", p.curToken.Literal)
+    }
+    p.skipSemicolons()
+    return statement
+}
+
+// parseImportStatement parses import "path" as name.
+func (p *Parser) parseImportStatement() ast.Statement {
+    defer p.untrace(p.trace("parseImportStatement"))
+    statement := &ast.ImportStatement{Token: p.curToken}
+    p.checkTopLevel()
+
+    if !p.expectPeek(token.STRING, "a module path after 'import'") {
+        return nil
+    }
+    statement.Path = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+
+    if !p.expectPeek(token.AS, "'as' after the module path") {
+        return nil
+    }
+    if !p.expectPeek(token.IDENT, "a name for the module after 'as'") {
+        return nil
+    }
+    statement.Alias = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+    p.skipSemicolons()
+    return statement
+}
+
+func (p *Parser) parseExportStatement() ast.Statement {
+    defer p.untrace(p.trace("parseExportStatement"))
+    statement := &ast.ExportStatement{Token: p.curToken}
+    p.checkTopLevel()
+
+    if !p.expectPeek(token.LET, "'let' after 'export'") {
+        return nil
+    }
+    let := p.parseLetStatement()
+    if let == nil {
+        return nil
+    }
+    statement.Statement = let.(*ast.LetStatement)
     return statement
 }
 
+// checkTopLevel reports the statement at curToken if it is inside a block.
+func (p *Parser) checkTopLevel() {
+    if p.blockDepth > 0 {
+        p.addError(
+            ErrNotTopLevel, p.curToken,
+            "'%s' is only allowed at the top level of a module", p.curToken.Literal)
+    }
+}
+
+// semicolons are optional after statements
+func (p *Parser) skipSemicolons() {
+    for p.isPeekToken(token.SEMICOLON) {
+        p.nextToken()
+    }
+}
+
 func (p *Parser) parseExpressionStatement() ast.Statement {
+    defer p.untrace(p.trace("parseExpressionStatement"))
     statement := &ast.ExpressionStatement{Token: p.curToken}
     statement.Expression = p.parseExpression(LOWEST)
-    
-    // semicolumns are optional in expressions
-    for p.isPeekToken(token.SEMICOLON) {
-        p.nextToken()
+    if statement.Expression == nil {
+        return nil
     }
+    p.skipSemicolons()
 
     return statement
 }
 
 func (p *Parser) parseExpression(priority int) ast.Expression {
+    defer p.untrace(p.traceExpression(priority))
     prefix := p.prefixParseFns[p.curToken.Type]
     if prefix == nil {
         p.addNoPrefixParseFnError(p.curToken.Type)
         return nil
     }
     leftExp := prefix()
+    if leftExp == nil {
+        return nil
+    }
 
     // i came from 'priority' expression,
     // if the next one is higher priority, we start a recursion where
     // im left for next one, else, next one is my right.
-    for !p.isPeekToken(token.SEMICOLON) && priority < p.peekPriority() {
+    for !p.isPeekToken(token.SEMICOLON) && p.bindsTighter(priority) {
         infix := p.infixParseFns[p.peekToken.Type]
         if infix == nil {
+            if p.tracer != nil {
+                p.tracef("no infix parser for %s, stop", traceToken(p.peekToken))
+            }
             return leftExp
         }
 
         p.nextToken()
         leftExp = infix(leftExp)
+        if leftExp == nil {
+            return nil
+        }
     }
 
     return leftExp
 }
 
+// bindsTighter reports whether the peek operator takes the expression
+// parsed so far as its left side, tracing the decision.
+func (p *Parser) bindsTighter(priority int) bool {
+    peek := p.peekPriority()
+    if p.tracer != nil {
+        if priority < peek {
+            p.tracef("%s < %s of %s, continue", priorityName(priority), priorityName(peek), traceToken(p.peekToken))
+        } else {
+            p.tracef("%s >= %s of %s, stop", priorityName(priority), priorityName(peek), traceToken(p.peekToken))
+        }
+    }
+    return priority < peek
+}
+
 func (p *Parser) parsePrefixExpression() ast.Expression {
+    defer p.untrace(p.trace("parsePrefixExpression"))
     expr := &ast.PrefixExpression{
         Token: p.curToken,
         Operator: p.curToken.Literal,
     }
 
+    priority := PREFIX
+    if op, ok := p.prefixOperators[expr.Token.Type]; ok && op.Priority != 0 {
+        priority = op.Priority
+    }
     p.nextToken()
-    expr.Right = p.parseExpression(PREFIX)
+    expr.Right = p.parseExpression(priority)
+    if expr.Right == nil {
+        return nil
+    }
     return expr
 }
 
+// rightPriority returns the priority to parse the right side of the infix
+// operator at curToken with.
+func (p *Parser) rightPriority() int {
+    op := p.infixOperators[p.curToken.Type]
+    if op.Associativity == RightAssoc {
+        // let operators of the same priority on the right win
+        if p.tracer != nil {
+            p.tracef("%s is right associative", traceToken(p.curToken))
+        }
+        return op.Priority - 1
+    }
+    return op.Priority
+}
+
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
+    defer p.untrace(p.trace("parseInfixExpression"))
     expression := &ast.InfixExpression{
         Token: p.curToken,
         Operator: p.curToken.Literal,
         Left: left,
     }
-    priority := p.curPriority()
+    priority := p.rightPriority()
     p.nextToken()
     
     // gonna parse right expression, but if its
     // made of higher priority operators, will return
     expression.Right = p.parseExpression(priority)
+    if expression.Right == nil {
+        return nil
+    }
+    return expression
+}
+
+// parseAssignExpression parses the value of an assignment. Targets other
+// than names and index expressions are reported, but the assignment is
+// kept so that the rest of the statement parses normally.
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+    defer p.untrace(p.trace("parseAssignExpression"))
+    expression := &ast.AssignExpression{
+        Token: p.curToken,
+        Target: target,
+        Operator: p.curToken.Literal,
+    }
+
+    switch target.(type) {
+    case *ast.Identifier, *ast.IndexExpression:
+    default:
+        p.addError(
+            ErrInvalidTarget, ast.StartOf(target),
+            "cannot assign to %s '%s', only to a name or an index expression",
+            describeExpression(target), target.String())
+    }
+
+    priority := p.rightPriority()
+    p.nextToken()
+
+    expression.Value = p.parseExpression(priority)
+    if expression.Value == nil {
+        return nil
+    }
     return expression
 }
 
@@ -259,9 +688,383 @@ func (p *Parser) parseIndentifier() ast.Expression {
 func (p *Parser) parseInteger() ast.Expression {
     value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
     if err != nil {
-        e := fmt.Sprintf("Could not parse %s as int64", p.curToken.Literal)
-        p.errors = append(p.errors, e)
+        p.addError(ErrInvalidNumber, p.curToken, "Could not parse %s as int64", p.curToken.Literal)
         return nil
     }
     return &ast.Integer{Token: p.curToken, Value: value}
 }
+
+func (p *Parser) parseFloat() ast.Expression {
+    value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+    if err != nil {
+        p.addError(
+            ErrInvalidNumber, p.curToken,
+            "Could not parse %s as float64", p.curToken.Literal)
+        return nil
+    }
+    return &ast.Float{Token: p.curToken, Value: value}
+}
+
+func (p *Parser) parseBoolean() ast.Expression {
+    return &ast.Boolean{Token: p.curToken, Value: p.isCurToken(token.TRUE)}
+}
+
+// parentheses only steer the parsing, they leave no node behind
+func (p *Parser) parseGroupedExpression() ast.Expression {
+    defer p.untrace(p.trace("parseGroupedExpression"))
+    p.nextToken()
+
+    expr := p.parseExpression(LOWEST)
+    if expr == nil {
+        return nil
+    }
+    if !p.nextIfPeek(token.RPAREN) {
+        return nil
+    }
+    return expr
+}
+
+func (p *Parser) parseStringLiteral() ast.Expression {
+    return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+}
+
+// the lexer already reported why the token is illegal
+func (p *Parser) parseIllegal() ast.Expression {
+    return nil
+}
+
+func (p *Parser) parseIfExpression() ast.Expression {
+    defer p.untrace(p.trace("parseIfExpression"))
+    expr := &ast.IfExpression{Token: p.curToken}
+
+    if !p.isPeekToken(token.LPAREN) {
+        p.addUnexpected(
+            p.peekToken, []token.TokenType{token.LPAREN},
+            "expected '(' after 'if', got %s", describe(p.peekToken))
+        return nil
+    }
+    p.nextToken()
+    if p.isPeekToken(token.RPAREN) {
+        p.addError(ErrMissingExpression, p.peekToken, "missing condition in if expression")
+        return nil
+    }
+    p.nextToken()
+
+    expr.Condition = p.parseExpression(LOWEST)
+    if expr.Condition == nil {
+        return nil
+    }
+    if !p.isPeekToken(token.RPAREN) {
+        p.addUnexpected(
+            p.peekToken, []token.TokenType{token.RPAREN},
+            "expected ')' after the if condition, got %s", describe(p.peekToken))
+        return nil
+    }
+    p.nextToken()
+
+    if !p.isPeekToken(token.LBRACE) {
+        p.addUnexpected(
+            p.peekToken, []token.TokenType{token.LBRACE},
+            "expected '{' after the if condition, got %s", describe(p.peekToken))
+        return nil
+    }
+    p.nextToken()
+    expr.Consequence = p.parseBlockStatement()
+    if expr.Consequence == nil {
+        return nil
+    }
+
+    if !p.isPeekToken(token.ELSE) {
+        return expr
+    }
+    p.nextToken()
+
+    switch {
+    case p.isPeekToken(token.IF):
+        p.nextToken()
+        alternative := p.parseIfExpression()
+        if alternative == nil {
+            return nil
+        }
+        expr.Alternative = alternative
+    case p.isPeekToken(token.LBRACE):
+        p.nextToken()
+        alternative := p.parseBlockStatement()
+        if alternative == nil {
+            return nil
+        }
+        expr.Alternative = alternative
+    default:
+        p.addUnexpected(
+            p.peekToken, []token.TokenType{token.LBRACE, token.IF},
+            "expected '{' or 'if' after 'else', got %s", describe(p.peekToken))
+        return nil
+    }
+    return expr
+}
+
+// parseBlockStatement parses statements up to the matching '}',
+// starting on the opening '{'.
+func (p *Parser) parseBlockStatement() *ast.BlockStatement {
+    defer p.untrace(p.trace("parseBlockStatement"))
+    block := &ast.BlockStatement{Token: p.curToken}
+    block.Statements = []ast.Statement{}
+    p.nextToken()
+
+    p.blockDepth += 1
+    defer func() { p.blockDepth -= 1 }()
+
+    for !p.isCurToken(token.RBRACE) {
+        if p.isCurToken(token.EOF) {
+            p.addError(
+                ErrUnclosed, p.curToken,
+                "expected '}' to close the block opened at %s", block.Token.Pos)
+            return nil
+        }
+
+        depth := p.outerDepth()
+        statement := p.parseStatement()
+        if statement != nil {
+            block.Statements = append(block.Statements, statement)
+        } else if p.isCurToken(token.RBRACE) && p.failedOnCurToken() {
+            // the '}' ending this block cut the statement short
+            continue
+        } else {
+            p.synchronize(depth)
+        }
+        if p.tooManyErrors() {
+            return nil
+        }
+        p.nextToken()
+    }
+    return block
+}
+
+func (p *Parser) parseFunctionLiteral() ast.Expression {
+    defer p.untrace(p.trace("parseFunctionLiteral"))
+    lit := &ast.FunctionLiteral{Token: p.curToken}
+
+    if !p.isPeekToken(token.LPAREN) {
+        p.addUnexpected(
+            p.peekToken, []token.TokenType{token.LPAREN},
+            "expected '(' after 'fn', got %s", describe(p.peekToken))
+        return nil
+    }
+    p.nextToken()
+
+    lit.Parameters, lit.ParameterTypes = p.parseFunctionParameters()
+    if lit.Parameters == nil {
+        return nil
+    }
+    if p.isPeekToken(token.ARROW) {
+        p.nextToken()
+        p.nextToken()
+        lit.ReturnType = p.parseType()
+        if lit.ReturnType == nil {
+            return nil
+        }
+    }
+
+    if !p.isPeekToken(token.LBRACE) {
+        p.addUnexpected(
+            p.peekToken, []token.TokenType{token.LBRACE},
+            "expected '{' to open the function body, got %s", describe(p.peekToken))
+        return nil
+    }
+    p.nextToken()
+
+    // break and continue can not reach loops outside the function
+    outerLoops := p.loopDepth
+    p.loopDepth = 0
+    lit.Body = p.parseBlockStatement()
+    p.loopDepth = outerLoops
+    if lit.Body == nil {
+        return nil
+    }
+    return lit
+}
+
+// parseFunctionParameters parses identifiers, each optionally annotated
+// with a type, up to the closing ')', starting on the '('. A trailing
+// comma is allowed, like in argument lists. It returns
+// the parameters and their types, nil where not annotated, or nil if the
+// list is malformed.
+func (p *Parser) parseFunctionParameters() ([]*ast.Identifier, []ast.Type) {
+    defer p.untrace(p.trace("parseFunctionParameters"))
+    params := []*ast.Identifier{}
+    types := []ast.Type{}
+    if p.isPeekToken(token.RPAREN) {
+        p.nextToken()
+        return params, types
+    }
+
+    seen := map[string]bool{}
+    for {
+        if !p.nextIfPeek(token.IDENT) {
+            return nil, nil
+        }
+        param := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+        if seen[param.Value] {
+            p.addError(ErrDuplicateName, p.curToken, "duplicate parameter name '%s'", param.Value)
+        }
+        seen[param.Value] = true
+        params = append(params, param)
+
+        annotation, ok := p.parseTypeAnnotation()
+        if !ok {
+            return nil, nil
+        }
+        types = append(types, annotation)
+
+        if !p.isPeekToken(token.COMMA) {
+            break
+        }
+        p.nextToken()
+        if p.isPeekToken(token.RPAREN) {
+            break
+        }
+    }
+
+    if !p.nextIfPeek(token.RPAREN) {
+        return nil, nil
+    }
+    return params, types
+}
+
+func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
+    defer p.untrace(p.trace("parseCallExpression"))
+    call := &ast.CallExpression{Token: p.curToken, Function: function}
+    call.Arguments = p.parseExpressionList(token.RPAREN)
+    if call.Arguments == nil {
+        return nil
+    }
+    return call
+}
+
+// parseExpressionList parses comma separated expressions up to the given
+// closing token, starting on the opening one. A trailing comma is allowed.
+// It returns nil if the list is malformed.
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+    defer p.untrace(p.trace("parseExpressionList"))
+    list := []ast.Expression{}
+    if p.isPeekToken(end) {
+        p.nextToken()
+        return list
+    }
+
+    for {
+        p.nextToken()
+        expr := p.parseExpression(LOWEST)
+        if expr == nil {
+            return nil
+        }
+        list = append(list, expr)
+
+        if !p.isPeekToken(token.COMMA) {
+            break
+        }
+        p.nextToken()
+        if p.isPeekToken(end) {
+            break
+        }
+    }
+
+    if !p.nextIfPeek(end) {
+        return nil
+    }
+    return list
+}
+
+func (p *Parser) parseArrayLiteral() ast.Expression {
+    defer p.untrace(p.trace("parseArrayLiteral"))
+    array := &ast.ArrayLiteral{Token: p.curToken}
+    array.Elements = p.parseExpressionList(token.RBRACKET)
+    if array.Elements == nil {
+        return nil
+    }
+    return array
+}
+
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+    defer p.untrace(p.trace("parseIndexExpression"))
+    expr := &ast.IndexExpression{Token: p.curToken, Left: left}
+
+    if p.isPeekToken(token.RBRACKET) {
+        p.addError(ErrMissingExpression, p.peekToken, "missing index expression")
+        return nil
+    }
+    p.nextToken()
+
+    expr.Index = p.parseExpression(LOWEST)
+    if expr.Index == nil {
+        return nil
+    }
+    if !p.nextIfPeek(token.RBRACKET) {
+        return nil
+    }
+    return expr
+}
+
+// parseHashLiteral parses {key: value, ...}. In expression position a '{'
+// always opens a hash, blocks only follow if, else and fn. Input that
+// looks like a block gets an error saying so.
+func (p *Parser) parseHashLiteral() ast.Expression {
+    defer p.untrace(p.trace("parseHashLiteral"))
+    hash := &ast.HashLiteral{Token: p.curToken}
+    hash.Pairs = []ast.HashPair{}
+
+    for !p.isPeekToken(token.RBRACE) {
+        if p.isPeekToken(token.EOF) {
+            p.addError(
+                ErrUnclosed, p.peekToken,
+                "expected '}' to close the hash opened at %s", hash.Token.Pos)
+            return nil
+        }
+        if statementStarts[p.peekToken.Type] && !p.isPeekToken(token.FUNCTION) {
+            p.addError(
+                ErrUnexpectedToken, p.peekToken,
+                "expected a hash key, got %s (blocks can only follow if, else, fn or loops)", describe(p.peekToken))
+            return nil
+        }
+        p.nextToken()
+
+        key := p.parseExpression(LOWEST)
+        if key == nil {
+            return nil
+        }
+        if !p.isPeekToken(token.COLON) {
+            if p.isPeekToken(token.SEMICOLON) || p.isPeekToken(token.RBRACE) {
+                p.addUnexpected(
+                    p.peekToken, []token.TokenType{token.COLON},
+                    "expected ':' after hash key, got %s (blocks can only follow if, else, fn or loops)", describe(p.peekToken))
+            } else {
+                p.addUnexpected(
+                    p.peekToken, []token.TokenType{token.COLON},
+                    "expected ':' after hash key, got %s", describe(p.peekToken))
+            }
+            return nil
+        }
+        p.nextToken()
+        p.nextToken()
+
+        value := p.parseExpression(LOWEST)
+        if value == nil {
+            return nil
+        }
+        hash.Pairs = append(hash.Pairs, ast.HashPair{Key: key, Value: value})
+
+        if !p.isPeekToken(token.COMMA) {
+            if !p.isPeekToken(token.RBRACE) {
+                p.addUnexpected(
+                    p.peekToken, []token.TokenType{token.COMMA, token.RBRACE},
+                    "expected ',' or '}' after hash value, got %s", describe(p.peekToken))
+                return nil
+            }
+            break
+        }
+        p.nextToken()
+    }
+    p.nextToken()
+
+    return hash
+}
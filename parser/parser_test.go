@@ -1,8 +1,10 @@
 package parser
 
 import (
+	"bytes"
 	"monke/ast"
 	"monke/lexer"
+	"strings"
 	"testing"
 )
 
@@ -27,7 +29,9 @@ func TestLetStatement(t *testing.T) {
     input := `
         let x = 5;
         let y = 101;
-        let foobar = 838383;
+        let foobar = y;
+        let z = 1 + 2 * 3
+        let w = "w";;
         `
 
 	l := lexer.New(input)
@@ -36,14 +40,17 @@ func TestLetStatement(t *testing.T) {
     testParserErrors(t, p)
 
     assertProgramOk(t, program)
-    assertStatementCount(t, program, 3)
+    assertStatementCount(t, program, 5)
 
     tests := []struct {
         expectedIdent string
+        expectedValue string
     } {
-        { "x" },
-        { "y" },
-        { "foobar" },
+        { "x", "5" },
+        { "y", "101" },
+        { "foobar", "y" },
+        { "z", "(1 + (2 * 3))" },
+        { "w", `"w"` },
     }
 
     for i, tt := range tests {
@@ -51,6 +58,132 @@ func TestLetStatement(t *testing.T) {
         if !testLetStatement(t, statement, tt.expectedIdent) {
             return
         }
+
+        value := statement.(*ast.LetStatement).Value
+        if value == nil {
+            t.Fatalf("statement %d has no value", i)
+        }
+        if value.String() != tt.expectedValue {
+            t.Errorf("expected value %q, got %q", tt.expectedValue, value.String())
+        }
+    }
+}
+
+func TestDestructuringLetStatement(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {"let [a, b] = pair;", "let [a, b] = pair;"},
+        {"let [head, ...tail] = list", "let [head, ...tail] = list;"},
+        {"let [_, [x, y]] = f()", "let [_, [x, y]] = f();"},
+        {"let {name, age: years} = person;", "let {name, age: years} = person;"},
+        {`let {"first name": first, pos: [x, y]} = p`, `let {"first name": first, pos: [x, y]} = p;`},
+        {"let [{id}, ...others] = users", "let [{id}, ...others] = users;"},
+    }
+
+    for _, test := range tests {
+        p := New(lexer.New(test.input))
+        program := p.ParseProgram()
+        testParserErrors(t, p)
+        assertStatementCount(t, program, 1)
+
+        statement, ok := program.Statements[0].(*ast.LetStatement)
+        if !ok {
+            t.Fatalf("expected *ast.LetStatement, got %T", program.Statements[0])
+        }
+        if statement.Pattern == nil || statement.Name != nil {
+            t.Errorf("%q - expected a pattern instead of a name", test.input)
+        }
+        if program.String() != test.expected {
+            t.Errorf("expected %q, got %q", test.expected, program.String())
+        }
+    }
+}
+
+func TestDestructuringErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {"let [a, b, a] = x", "1:12: duplicate name 'a' in pattern"},
+        {"let {a, b: [c, a]} = x", "1:16: duplicate name 'a' in pattern"},
+        {"let [a, ...a] = x", "1:12: duplicate name 'a' in pattern"},
+        {"let [a, 1] = x", "1:9: cannot bind to literal pattern '1' in let, only to names and destructuring patterns"},
+        {"let [...rest, last] = x", "1:13: expected ']' after the rest element, it has to be last, got ','"},
+        {"let [...] = x", "1:9: expected a name after '...', got ']'"},
+        {"let [a b] = x", "1:8: expected ',' or ']' in array pattern, got 'b'"},
+        {"let [a] x", "1:9: expected next token '=', got 'IDENT'"},
+    }
+
+    for _, test := range tests {
+        p := New(lexer.New(test.input))
+        p.ParseProgram()
+
+        errors := p.Errors()
+        if len(errors) != 1 || errors[0] != test.expected {
+            t.Errorf("%q - expected error %q, got %v", test.input, test.expected, errors)
+        }
+    }
+}
+
+func TestImportAndExport(t *testing.T) {
+    input := `
+        import "lib/math.monke" as math;
+        import "util.monke" as util
+        export let add = fn(a, b) { a + b };
+        export let [x, y] = [1, 2]`
+
+    p := New(lexer.New(input))
+    program := p.ParseProgram()
+    testParserErrors(t, p)
+    assertStatementCount(t, program, 4)
+
+    imported, ok := program.Statements[0].(*ast.ImportStatement)
+    if !ok {
+        t.Fatalf("expected *ast.ImportStatement, got %T", program.Statements[0])
+    }
+    if imported.Path.Value != "lib/math.monke" || imported.Alias.Value != "math" {
+        t.Errorf("expected lib/math.monke as math, got %s as %s", imported.Path.Value, imported.Alias.Value)
+    }
+
+    exported, ok := program.Statements[2].(*ast.ExportStatement)
+    if !ok {
+        t.Fatalf("expected *ast.ExportStatement, got %T", program.Statements[2])
+    }
+    if exported.Statement.Name.Value != "add" {
+        t.Errorf("expected add to be exported, got %q", exported.Statement.Name.Value)
+    }
+
+    expected := `import "lib/math.monke" as math;import "util.monke" as util;` +
+        `export let add = fn(a, b) { (a + b) };export let [x, y] = [1, 2];`
+    if program.String() != expected {
+        t.Errorf("expected %q, got %q", expected, program.String())
+    }
+}
+
+func TestImportAndExportErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {"import lib as l", "1:8: expected a module path after 'import', got 'lib'"},
+        {`import "lib"`, "1:13: expected 'as' after the module path, got end of input"},
+        {`import "lib" as "l"`, "1:17: expected a name for the module after 'as', got string"},
+        {"export fn() {}", "1:8: expected 'let' after 'export', got 'fn'"},
+        {"export let = 1", "1:12: expected next token 'IDENT', got '='"},
+        {`if (x) { import "lib" as lib }`, "1:10: 'import' is only allowed at the top level of a module"},
+        {"let f = fn() { export let x = 1; }", "1:16: 'export' is only allowed at the top level of a module"},
+    }
+
+    for _, test := range tests {
+        p := New(lexer.New(test.input))
+        p.ParseProgram()
+
+        errors := p.Errors()
+        if len(errors) != 1 || errors[0] != test.expected {
+            t.Errorf("%q - expected error %q, got %v", test.input, test.expected, errors)
+        }
     }
 }
 
@@ -58,8 +191,9 @@ func TestReturnStatement(t *testing.T) {
     input := `
         return x;
         return 513123;
-        return 5 * x;
-        `
+        return 5 * x
+        return;
+        return`
 
 	l := lexer.New(input)
     p := New(l)
@@ -67,11 +201,30 @@ func TestReturnStatement(t *testing.T) {
     testParserErrors(t, p)
 
     assertProgramOk(t, program)
-    assertStatementCount(t, program, 3)
+    assertStatementCount(t, program, 5)
     
     for _, s := range program.Statements {
         assertOkReturnStatement(t, s)
     }
+
+    expectedValues := []string{"x", "513123", "(5 * x)", "", ""}
+    for i, expected := range expectedValues {
+        value := program.Statements[i].(*ast.ReturnStatement).Value
+        if expected == "" {
+            if value != nil {
+                t.Errorf("expected bare return, got %q", value.String())
+            }
+            continue
+        }
+        if value == nil || value.String() != expected {
+            t.Errorf("expected return value %q, got %v", expected, value)
+        }
+    }
+
+    expectedString := "return x;return 513123;return (5 * x);return;return;"
+    if program.String() != expectedString {
+        t.Errorf("expected %q, got %q", expectedString, program.String())
+    }
 }
 func assertOkReturnStatement(t *testing.T, s ast.Statement) {
     if s.TokenLiteral() != "return" {
@@ -131,3 +284,305 @@ func assertStatementCount(t *testing.T, p *ast.Program, count int) {
         t.Fatalf("Expected %d statements, got %d", count, nStatements)
     }
 }
+
+func TestLetStatementErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {"let x 5;", "1:7: expected next token '=', got 'INT'"},
+        {"let = 5;", "1:5: expected next token 'IDENT', got '='"},
+        {"let x = ;", "1:9: No prefix parser for token ';'"},
+    }
+
+    for _, test := range tests {
+        p := New(lexer.New(test.input))
+        program := p.ParseProgram()
+
+        errors := p.Errors()
+        if len(errors) == 0 || errors[0] != test.expected {
+            t.Errorf("expected first error %q, got %v", test.expected, errors)
+        }
+        for _, s := range program.Statements {
+            if _, ok := s.(*ast.LetStatement); ok {
+                t.Errorf("expected no let statement for %q, got %q", test.input, s.String())
+            }
+        }
+    }
+}
+
+func TestCommentsAreCollected(t *testing.T) {
+    input := `
+        // the answer
+        let x = 42; /* not 41 */
+        `
+
+    l := lexer.New(input, lexer.WithComments())
+    p := New(l)
+    program := p.ParseProgram()
+    testParserErrors(t, p)
+
+    assertStatementCount(t, program, 1)
+    if len(program.Comments) != 2 {
+        t.Fatalf("expected 2 comments, got %d", len(program.Comments))
+    }
+    if program.Comments[0].Literal != "// the answer" {
+        t.Errorf("unexpected first comment %q", program.Comments[0].Literal)
+    }
+    if program.Comments[1].Literal != "/* not 41 */" {
+        t.Errorf("unexpected second comment %q", program.Comments[1].Literal)
+    }
+}
+
+func TestWhileStatement(t *testing.T) {
+    input := `while (i < 10) { i; break; continue }`
+
+    p := New(lexer.New(input))
+    program := p.ParseProgram()
+    testParserErrors(t, p)
+    assertStatementCount(t, program, 1)
+
+    statement, ok := program.Statements[0].(*ast.WhileStatement)
+    if !ok {
+        t.Fatalf("expected *ast.WhileStatement, got %T", program.Statements[0])
+    }
+    if statement.Condition.String() != "(i < 10)" {
+        t.Errorf("expected condition (i < 10), got %q", statement.Condition.String())
+    }
+    if len(statement.Body.Statements) != 3 {
+        t.Fatalf("expected 3 statements in the body, got %d", len(statement.Body.Statements))
+    }
+    if _, ok := statement.Body.Statements[1].(*ast.BreakStatement); !ok {
+        t.Errorf("expected *ast.BreakStatement, got %T", statement.Body.Statements[1])
+    }
+    if _, ok := statement.Body.Statements[2].(*ast.ContinueStatement); !ok {
+        t.Errorf("expected *ast.ContinueStatement, got %T", statement.Body.Statements[2])
+    }
+
+    expected := "while (i < 10) { i break; continue; }"
+    if program.String() != expected {
+        t.Errorf("expected %q, got %q", expected, program.String())
+    }
+}
+
+func TestForStatement(t *testing.T) {
+    input := `for (x in [1, 2, 3]) { for (y in f(x)) { if (y) { continue } } }`
+
+    p := New(lexer.New(input))
+    program := p.ParseProgram()
+    testParserErrors(t, p)
+    assertStatementCount(t, program, 1)
+
+    statement, ok := program.Statements[0].(*ast.ForStatement)
+    if !ok {
+        t.Fatalf("expected *ast.ForStatement, got %T", program.Statements[0])
+    }
+    if statement.Variable.Value != "x" {
+        t.Errorf("expected loop variable x, got %q", statement.Variable.Value)
+    }
+    if statement.Iterable.String() != "[1, 2, 3]" {
+        t.Errorf("expected iterable [1, 2, 3], got %q", statement.Iterable.String())
+    }
+
+    expected := "for (x in [1, 2, 3]) { for (y in f(x)) { if (y) { continue; } } }"
+    if program.String() != expected {
+        t.Errorf("expected %q, got %q", expected, program.String())
+    }
+}
+
+func TestLoopErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {"break", "1:1: 'break' outside of a loop"},
+        {"if (x) { continue; }", "1:10: 'continue' outside of a loop"},
+        {"while (x) { fn() { break } }", "1:20: 'break' outside of a loop"},
+        {"while x { }", "1:7: expected '(' after 'while', got 'x'"},
+        {"while () { }", "1:8: missing condition in while loop"},
+        {"while (x) y", "1:11: expected '{' to open the loop body, got 'y'"},
+        {"for (1 in xs) {}", "1:6: expected a loop variable name, got '1'"},
+        {"for (x of xs) {}", "1:8: expected 'in' after the loop variable, got 'of'"},
+        {"for (x in xs {}", "1:14: expected ')' after the iterated expression, got '{'"},
+    }
+
+    for _, test := range tests {
+        p := New(lexer.New(test.input))
+        p.ParseProgram()
+
+        errors := p.Errors()
+        if len(errors) != 1 || errors[0] != test.expected {
+            t.Errorf("%q - expected error %q, got %v", test.input, test.expected, errors)
+        }
+    }
+
+    // misplaced break and continue are kept, so only they get reported
+    p := New(lexer.New("break; let x = 1; continue"))
+    program := p.ParseProgram()
+    if program.String() != "break;let x = 1;continue;" || len(p.Errors()) != 2 {
+        t.Errorf("expected both statements kept with 2 errors, got %q and %v", program.String(), p.Errors())
+    }
+}
+
+func TestErrorRecovery(t *testing.T) {
+    input := `
+        let a = 1;
+        let b 2;
+        let c = 3;
+        let d = * 4;
+        let e = 5
+        return )
+        let f = fn(x) { x + };
+        let g = 7;
+        }
+        let h = [1, 2
+        let i = 9;
+        let j = {"a" 1};
+        let k = 11;
+        {x}
+        let l = 13;
+        `
+
+    p := New(lexer.New(input))
+    program := p.ParseProgram()
+
+    expectedStatements := []string{
+        "let a = 1;",
+        "let c = 3;",
+        "let e = 5;",
+        // the broken statement in the body is dropped, the function kept
+        "let f = fn(x) {};",
+        "let g = 7;",
+        "let i = 9;",
+        "let k = 11;",
+        "let l = 13;",
+    }
+    if len(program.Statements) != len(expectedStatements) {
+        t.Fatalf("expected statements %v, got %q", expectedStatements, program.String())
+    }
+    for i, expected := range expectedStatements {
+        if program.Statements[i].String() != expected {
+            t.Errorf("expected statement %q, got %q", expected, program.Statements[i].String())
+        }
+    }
+
+    expectedErrors := []string{
+        "3:15: expected next token '=', got 'INT'",
+        "5:17: No prefix parser for token '*'",
+        "7:16: No prefix parser for token ')'",
+        "8:29: No prefix parser for token '}'",
+        "10:9: No prefix parser for token '}'",
+        "12:9: expected next token ']', got 'LET'",
+        "13:22: expected ':' after hash key, got '1'",
+        "15:11: expected ':' after hash key, got '}' (blocks can only follow if, else, fn or loops)",
+    }
+    errors := p.Errors()
+    if len(errors) != len(expectedErrors) {
+        t.Fatalf("expected errors %v, got %v", expectedErrors, errors)
+    }
+    for i, expected := range expectedErrors {
+        if errors[i] != expected {
+            t.Errorf("expected error %q, got %q", expected, errors[i])
+        }
+    }
+}
+
+func TestErrorRecoveryInBlocks(t *testing.T) {
+    input := `
+        if (x) {
+            let a = ;
+            let b = 2;
+            1 +
+        } else {
+            f(1, 2 }
+        let c = 3;
+        let d = fn(x) { let e = {1 2}; x };
+        if (y) { let h = {1: 2 3}; let i = 4; }
+        let j = 5;
+        `
+
+    p := New(lexer.New(input))
+    program := p.ParseProgram()
+
+    // the braces of a broken hash do not close the block around it
+    expected := "if (x) { let b = 2; } else {}let c = 3;let d = fn(x) { x };if (y) { let i = 4; }let j = 5;"
+    if program.String() != expected {
+        t.Errorf("expected %q, got %q", expected, program.String())
+    }
+    if len(p.Errors()) != 5 {
+        t.Errorf("expected 5 errors, got %v", p.Errors())
+    }
+}
+
+func TestMaxErrors(t *testing.T) {
+    input := `
+        let a 1; let b 2; let c 3;
+        let d 4; let e 5;
+        `
+
+    p := New(lexer.New(input), WithMaxErrors(3))
+    p.ParseProgram()
+
+    errors := p.ErrorList()
+    if len(errors) != 4 {
+        t.Fatalf("expected 3 errors and a final one, got %v", errors.Strings())
+    }
+    last := errors[3]
+    if last.Code != ErrTooManyErrors || last.Error() != "3:15: too many errors" {
+        t.Errorf("expected too many errors at 3:15, got %q", last.Error())
+    }
+
+    p = New(lexer.New(input), WithMaxErrors(0))
+    p.ParseProgram()
+    if len(p.Errors()) != 5 {
+        t.Errorf("expected all 5 errors without a limit, got %v", p.Errors())
+    }
+}
+
+func TestTrace(t *testing.T) {
+    var out bytes.Buffer
+    p := New(lexer.New("1 + 2 * 3"), WithTrace(&out))
+    p.ParseProgram()
+
+    expected := `START parseStatement cur=INT(1) peek=+
+  START parseExpressionStatement cur=INT(1) peek=+
+    START parseExpression(LOWEST) cur=INT(1) peek=+
+      LOWEST < SUM of +, continue
+      START parseInfixExpression cur=+ peek=INT(2)
+        START parseExpression(SUM) cur=INT(2) peek=*
+          SUM < PRODUCT of *, continue
+          START parseInfixExpression cur=* peek=INT(3)
+            START parseExpression(PRODUCT) cur=INT(3) peek=EOF
+              PRODUCT >= LOWEST of EOF, stop
+            END parseExpression(PRODUCT)
+          END parseInfixExpression
+          SUM >= LOWEST of EOF, stop
+        END parseExpression(SUM)
+      END parseInfixExpression
+      LOWEST >= LOWEST of EOF, stop
+    END parseExpression(LOWEST)
+  END parseExpressionStatement
+END parseStatement
+`
+    if out.String() != expected {
+        t.Errorf("expected trace:\n%s\ngot:\n%s", expected, out.String())
+    }
+
+    // errors unwind the same way
+    out.Reset()
+    New(lexer.New("f(1, "), WithTrace(&out)).ParseProgram()
+    if strings.Count(out.String(), "START") != strings.Count(out.String(), "END") {
+        t.Errorf("expected balanced trace, got:\n%s", out.String())
+    }
+}
+
+func TestTraceOffDoesNotAllocate(t *testing.T) {
+    p := New(lexer.New("1"))
+    allocs := testing.AllocsPerRun(100, func() {
+        p.untrace(p.traceExpression(SUM + 5))
+        p.bindsTighter(LOWEST)
+    })
+    if allocs != 0 {
+        t.Errorf("expected no allocations without tracing, got %v", allocs)
+    }
+}
@@ -0,0 +1,310 @@
+package parser
+
+import (
+	"monke/ast"
+	"monke/token"
+)
+
+// parseMatchExpression parses
+//
+//     match (subject) { pattern => body, pattern if guard => body, ... }
+//
+// Arms after one that matches anything without a guard are reported as
+// unreachable.
+func (p *Parser) parseMatchExpression() ast.Expression {
+    defer p.untrace(p.trace("parseMatchExpression"))
+    expr := &ast.MatchExpression{Token: p.curToken, Arms: []*ast.MatchArm{}}
+
+    if !p.expectPeek(token.LPAREN, "'(' after 'match'") {
+        return nil
+    }
+    if p.isPeekToken(token.RPAREN) {
+        p.addError(ErrMissingExpression, p.peekToken, "missing subject in match expression")
+        return nil
+    }
+    p.nextToken()
+
+    expr.Subject = p.parseExpression(LOWEST)
+    if expr.Subject == nil {
+        return nil
+    }
+    if !p.expectPeek(token.RPAREN, "')' after the match subject") {
+        return nil
+    }
+    if !p.expectPeek(token.LBRACE, "'{' to open the match arms") {
+        return nil
+    }
+    open := p.curToken
+    depth := p.braceDepth
+
+    var catchAll *ast.MatchArm
+    for !p.isPeekToken(token.RBRACE) {
+        if p.isPeekToken(token.EOF) {
+            p.addError(
+                ErrUnclosed, p.peekToken,
+                "expected '}' to close the match opened at %s", open.Pos)
+            return nil
+        }
+        p.nextToken()
+        start := p.curToken
+
+        arm := p.parseMatchArm()
+        if arm == nil {
+            p.skipToClosingBrace(depth)
+            return nil
+        }
+        if catchAll != nil {
+            p.addError(
+                ErrUnreachable, start,
+                "unreachable match arm, '%s' at %s already matches everything",
+                catchAll.Pattern.String(), startOfPattern(catchAll.Pattern).Pos)
+        } else if arm.Guard == nil && matchesAnything(arm.Pattern) {
+            catchAll = arm
+        }
+        expr.Arms = append(expr.Arms, arm)
+
+        // '}' and the end of input are left to the loop condition
+        if p.isPeekToken(token.COMMA) {
+            p.nextToken()
+        } else if !p.isPeekToken(token.RBRACE) && !p.isPeekToken(token.EOF) {
+            p.addUnexpected(
+                p.peekToken, []token.TokenType{token.COMMA, token.RBRACE},
+                "expected ',' or '}' after match arm, got %s", describe(p.peekToken))
+            p.skipToClosingBrace(depth)
+            return nil
+        }
+    }
+    p.nextToken()
+
+    return expr
+}
+
+// skipToClosingBrace skips to the '}' closing the brace that was open at
+// the given depth, so that the error which stopped a match does not break
+// up the statement around it.
+func (p *Parser) skipToClosingBrace(depth int) {
+    for !p.isCurToken(token.EOF) && !(p.isCurToken(token.RBRACE) && p.braceDepth < depth) {
+        p.nextToken()
+    }
+}
+
+// parseMatchArm parses pattern [if guard] => body, starting on the pattern.
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+    arm := &ast.MatchArm{}
+
+    arm.Pattern = p.parsePattern()
+    if arm.Pattern == nil {
+        return nil
+    }
+    p.checkBindings(arm.Pattern)
+
+    if p.isPeekToken(token.IF) {
+        p.nextToken()
+        p.nextToken()
+        arm.Guard = p.parseExpression(LOWEST)
+        if arm.Guard == nil {
+            return nil
+        }
+    }
+
+    if !p.expectPeek(token.FAT_ARROW, "'=>' after the pattern") {
+        return nil
+    }
+    p.nextToken()
+
+    arm.Body = p.parseExpression(LOWEST)
+    if arm.Body == nil {
+        return nil
+    }
+    return arm
+}
+
+// parsePattern parses a pattern starting on its first token:
+//
+//     _  x  1  -2.5  "s"  true  [a, [b, _]]  {name, "age": 30}
+func (p *Parser) parsePattern() ast.Pattern {
+    defer p.untrace(p.trace("parsePattern"))
+
+    switch p.curToken.Type {
+    case token.IDENT:
+        if p.curToken.Literal == "_" {
+            return &ast.WildcardPattern{Token: p.curToken}
+        }
+        return &ast.BindingPattern{Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+    case token.INT, token.FLOAT, token.STRING, token.TRUE, token.FALSE:
+        value := p.prefixParseFns[p.curToken.Type]()
+        if value == nil {
+            return nil
+        }
+        return &ast.LiteralPattern{Value: value}
+    case token.MINUS:
+        if p.isPeekToken(token.INT) || p.isPeekToken(token.FLOAT) {
+            minus := &ast.PrefixExpression{Token: p.curToken, Operator: p.curToken.Literal}
+            p.nextToken()
+            minus.Right = p.prefixParseFns[p.curToken.Type]()
+            if minus.Right == nil {
+                return nil
+            }
+            return &ast.LiteralPattern{Value: minus}
+        }
+    case token.LBRACKET:
+        return p.parseArrayPattern()
+    case token.LBRACE:
+        return p.parseHashPattern()
+    }
+
+    p.addError(ErrUnexpectedToken, p.curToken, "expected a pattern, got %s", describe(p.curToken))
+    return nil
+}
+
+// parseArrayPattern parses [pattern, ...], optionally ending in ...rest.
+func (p *Parser) parseArrayPattern() ast.Pattern {
+    pattern := &ast.ArrayPattern{Token: p.curToken, Elements: []ast.Pattern{}}
+
+    for !p.isPeekToken(token.RBRACKET) {
+        p.nextToken()
+        if p.isCurToken(token.ELLIPSIS) {
+            if !p.expectPeek(token.IDENT, "a name after '...'") {
+                return nil
+            }
+            pattern.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+            if !p.expectPeek(token.RBRACKET, "']' after the rest element, it has to be last") {
+                return nil
+            }
+            return pattern
+        }
+
+        element := p.parsePattern()
+        if element == nil {
+            return nil
+        }
+        pattern.Elements = append(pattern.Elements, element)
+
+        if !p.isPeekToken(token.COMMA) {
+            break
+        }
+        p.nextToken()
+    }
+
+    if !p.expectPeek(token.RBRACKET, "',' or ']' in array pattern") {
+        return nil
+    }
+    return pattern
+}
+
+// parseHashPattern parses {key: pattern, ...}, where a key is a name or a
+// literal, and a name alone binds the entry of that name to it.
+func (p *Parser) parseHashPattern() ast.Pattern {
+    pattern := &ast.HashPattern{Token: p.curToken, Pairs: []ast.HashPatternPair{}}
+
+    for !p.isPeekToken(token.RBRACE) {
+        p.nextToken()
+
+        var pair ast.HashPatternPair
+        switch p.curToken.Type {
+        case token.IDENT:
+            name := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+            pair.Key = name
+            pair.Value = &ast.BindingPattern{Name: name}
+        case token.INT, token.STRING, token.TRUE, token.FALSE:
+            pair.Key = p.prefixParseFns[p.curToken.Type]()
+            if pair.Key == nil {
+                return nil
+            }
+            if !p.isPeekToken(token.COLON) {
+                p.addUnexpected(
+                    p.peekToken, []token.TokenType{token.COLON},
+                    "expected ':' after hash pattern key, got %s", describe(p.peekToken))
+                return nil
+            }
+        default:
+            p.addError(ErrUnexpectedToken, p.curToken, "expected a hash pattern key, got %s", describe(p.curToken))
+            return nil
+        }
+
+        if p.isPeekToken(token.COLON) {
+            p.nextToken()
+            p.nextToken()
+            pair.Value = p.parsePattern()
+            if pair.Value == nil {
+                return nil
+            }
+        }
+        pattern.Pairs = append(pattern.Pairs, pair)
+
+        if !p.isPeekToken(token.COMMA) {
+            break
+        }
+        p.nextToken()
+    }
+
+    if !p.expectPeek(token.RBRACE, "',' or '}' in hash pattern") {
+        return nil
+    }
+    return pattern
+}
+
+// checkBindings reports names bound more than once in one pattern.
+func (p *Parser) checkBindings(pattern ast.Pattern) {
+    seen := map[string]bool{}
+    for _, name := range ast.BoundNames(pattern) {
+        if seen[name.Value] {
+            p.addError(ErrDuplicateName, name.Token, "duplicate name '%s' in pattern", name.Value)
+        }
+        seen[name.Value] = true
+    }
+}
+
+// checkIrrefutable reports literal patterns, which could fail to match
+// where there is nothing to fall back on, like in a let.
+func (p *Parser) checkIrrefutable(pattern ast.Pattern) {
+    walkPattern(pattern, func(pattern ast.Pattern) {
+        if literal, ok := pattern.(*ast.LiteralPattern); ok {
+            p.addError(
+                ErrInvalidTarget, startOfPattern(literal),
+                "cannot bind to literal pattern '%s' in let, only to names and destructuring patterns",
+                literal.String())
+        }
+    })
+}
+
+// walkPattern calls visit for every pattern nested in pattern and then
+// for pattern itself.
+func walkPattern(pattern ast.Pattern, visit func(ast.Pattern)) {
+    switch pattern := pattern.(type) {
+    case *ast.ArrayPattern:
+        for _, element := range pattern.Elements {
+            walkPattern(element, visit)
+        }
+    case *ast.HashPattern:
+        for _, pair := range pattern.Pairs {
+            walkPattern(pair.Value, visit)
+        }
+    }
+    visit(pattern)
+}
+
+// matchesAnything reports whether a pattern can not fail to match.
+func matchesAnything(pattern ast.Pattern) bool {
+    switch pattern.(type) {
+    case *ast.WildcardPattern, *ast.BindingPattern:
+        return true
+    }
+    return false
+}
+
+func startOfPattern(pattern ast.Pattern) token.Token {
+    switch pattern := pattern.(type) {
+    case *ast.WildcardPattern:
+        return pattern.Token
+    case *ast.BindingPattern:
+        return pattern.Name.Token
+    case *ast.LiteralPattern:
+        return ast.StartOf(pattern.Value)
+    case *ast.ArrayPattern:
+        return pattern.Token
+    case *ast.HashPattern:
+        return pattern.Token
+    }
+    return token.Token{}
+}
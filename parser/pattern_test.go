@@ -0,0 +1,152 @@
+package parser
+
+import (
+	"monke/ast"
+	"monke/lexer"
+	"testing"
+)
+
+func TestMatchExpression(t *testing.T) {
+    input := `match (x) {
+        0 => "zero",
+        n if n < 0 => "negative",
+        [a, _] => a,
+        _ => "other",
+    }`
+
+    p := New(lexer.New(input))
+    program := p.ParseProgram()
+    testParserErrors(t, p)
+    assertStatementCount(t, program, 1)
+
+    statement := program.Statements[0].(*ast.ExpressionStatement)
+    match, ok := statement.Expression.(*ast.MatchExpression)
+    if !ok {
+        t.Fatalf("expected *ast.MatchExpression, got %T", statement.Expression)
+    }
+    if match.Subject.String() != "x" {
+        t.Errorf("expected subject x, got %q", match.Subject.String())
+    }
+    if len(match.Arms) != 4 {
+        t.Fatalf("expected 4 arms, got %d", len(match.Arms))
+    }
+
+    if _, ok := match.Arms[0].Pattern.(*ast.LiteralPattern); !ok {
+        t.Errorf("expected *ast.LiteralPattern, got %T", match.Arms[0].Pattern)
+    }
+    if _, ok := match.Arms[1].Pattern.(*ast.BindingPattern); !ok {
+        t.Errorf("expected *ast.BindingPattern, got %T", match.Arms[1].Pattern)
+    }
+    if match.Arms[1].Guard == nil || match.Arms[1].Guard.String() != "(n < 0)" {
+        t.Errorf("expected guard (n < 0), got %v", match.Arms[1].Guard)
+    }
+    if _, ok := match.Arms[2].Pattern.(*ast.ArrayPattern); !ok {
+        t.Errorf("expected *ast.ArrayPattern, got %T", match.Arms[2].Pattern)
+    }
+    if _, ok := match.Arms[3].Pattern.(*ast.WildcardPattern); !ok {
+        t.Errorf("expected *ast.WildcardPattern, got %T", match.Arms[3].Pattern)
+    }
+
+    expected := `match (x) { 0 => "zero", n if (n < 0) => "negative", [a, _] => a, _ => "other" }`
+    if program.String() != expected {
+        t.Errorf("expected %q, got %q", expected, program.String())
+    }
+}
+
+func TestPatterns(t *testing.T) {
+    tests := []struct {
+        pattern string
+        expected string
+    }{
+        {"_", "_"},
+        {"x", "x"},
+        {"-1", "(-1)"},
+        {"2.5", "2.5"},
+        {`"s"`, `"s"`},
+        {"true", "true"},
+        {"[]", "[]"},
+        {"[a, [b, _], 3,]", "[a, [b, _], 3]"},
+        {"[first, ...rest]", "[first, ...rest]"},
+        {"[...all]", "[...all]"},
+        {"{}", "{}"},
+        {`{name, "age": 30, 1: [x]}`, `{name, "age": 30, 1: [x]}`},
+        {"{name: n, user: {id}}", "{name: n, user: {id}}"},
+    }
+
+    for _, test := range tests {
+        input := "match (v) { " + test.pattern + " => 1 }"
+        p := New(lexer.New(input))
+        program := p.ParseProgram()
+        testParserErrors(t, p)
+
+        expected := "match (v) { " + test.expected + " => 1 }"
+        if program.String() != expected {
+            t.Errorf("%q - expected %q, got %q", test.pattern, expected, program.String())
+        }
+    }
+}
+
+func TestMatchErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        expected []string
+    }{
+        {"match x {}", []string{"1:7: expected '(' after 'match', got 'x'"}},
+        {"match () {}", []string{"1:8: missing subject in match expression"}},
+        {"match (x) { 1 2 }", []string{"1:15: expected '=>' after the pattern, got '2'"}},
+        {"match (x) { 1 => 2 3 => 4 }", []string{"1:20: expected ',' or '}' after match arm, got '3'"}},
+        {"match (x) { f(1) => 2 }", []string{"1:14: expected '=>' after the pattern, got '('"}},
+        {"match (x) { a + 1 => 2 }", []string{"1:15: expected '=>' after the pattern, got '+'"}},
+        {"match (x) { [1, 2 => 3 }", []string{"1:19: expected ',' or ']' in array pattern, got '=>'"}},
+        {"match (x) { {a: } => 3 }", []string{"1:17: expected a pattern, got '}'"}},
+        {"match (x) { {[1]: a} => 3 }", []string{"1:14: expected a hash pattern key, got '['"}},
+        {"match (x) { 1 => 2", []string{"1:19: expected '}' to close the match opened at 1:11"}},
+        {
+            "match (x) { _ => 1, 2 => 2, n if n > 0 => 3 }",
+            []string{
+                "1:21: unreachable match arm, '_' at 1:13 already matches everything",
+                "1:29: unreachable match arm, '_' at 1:13 already matches everything",
+            },
+        },
+        {"match (x) { [a, a] => 1 }", []string{"1:17: duplicate name 'a' in pattern"}},
+        {"match (x) { [_, _] => 1 }", nil},
+        // guarded catch-alls do not make later arms unreachable
+        {"match (x) { n if n > 0 => 1, y => 2 }", nil},
+    }
+
+    for _, test := range tests {
+        p := New(lexer.New(test.input))
+        p.ParseProgram()
+
+        errors := p.Errors()
+        if len(errors) != len(test.expected) {
+            t.Errorf("%q - expected errors %v, got %v", test.input, test.expected, errors)
+            continue
+        }
+        for i := range errors {
+            if errors[i] != test.expected[i] {
+                t.Errorf("%q - expected error %q, got %q", test.input, test.expected[i], errors[i])
+            }
+        }
+    }
+}
+
+func TestMatchErrorRecovery(t *testing.T) {
+    input := `
+        let a = match (x) { [1, 2 => 3, _ => 4 };
+        let b = 2;
+        match (y) { {k: 1} => { "hash": 1 } 2 => 3 }
+        let c = 3;
+        `
+
+    p := New(lexer.New(input))
+    program := p.ParseProgram()
+
+    expected := "let b = 2;let c = 3;"
+    if program.String() != expected {
+        t.Errorf("expected %q, got %q", expected, program.String())
+    }
+    if len(p.Errors()) != 2 {
+        t.Errorf("expected 2 errors, got %v", p.Errors())
+    }
+}
@@ -0,0 +1,86 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"monke/debug"
+	"monke/token"
+)
+
+// WithTrace logs every parse function entered and left to w, indented by
+// nesting depth, with the current and peek tokens and the priority
+// comparisons that decide how expressions group.
+func WithTrace(w io.Writer) Option {
+    return func(p *Parser) {
+        p.tracer = debug.NewTracer(w)
+    }
+}
+
+var priorityNames = map[int]string {
+    LOWEST: "LOWEST",
+    ASSIGN: "ASSIGN",
+    OR: "OR",
+    AND: "AND",
+    EQUALS: "EQUALS",
+    LESSGREATER: "LESSGREATER",
+    SUM: "SUM",
+    PRODUCT: "PRODUCT",
+    PREFIX: "PREFIX",
+    POWER: "POWER",
+    CALL: "CALL",
+    INDEX: "INDEX",
+}
+
+func priorityName(priority int) string {
+    if name, ok := priorityNames[priority]; ok {
+        return name
+    }
+    // an added operator between two levels
+    if name, ok := priorityNames[priority - priority % 10]; ok {
+        return fmt.Sprintf("%s+%d", name, priority % 10)
+    }
+    return fmt.Sprint(priority)
+}
+
+// trace logs entering the parse function name, to be closed with
+//
+//     defer p.untrace(p.trace("parseSomething"))
+func (p *Parser) trace(name string) string {
+    if p.tracer != nil {
+        p.tracer.Trace(fmt.Sprintf("%s cur=%s peek=%s", name, traceToken(p.curToken), traceToken(p.peekToken)))
+    }
+    return name
+}
+
+// traceExpression is trace for parseExpression, which runs too often to
+// build its name with the priority when tracing is off.
+func (p *Parser) traceExpression(priority int) string {
+    if p.tracer == nil {
+        return ""
+    }
+    return p.trace("parseExpression(" + priorityName(priority) + ")")
+}
+
+func (p *Parser) untrace(name string) {
+    if p.tracer != nil {
+        p.tracer.Un(name)
+    }
+}
+
+// tracef logs a message. Arguments are evaluated even when tracing is off,
+// so callers check p.tracer before formatting anything expensive.
+func (p *Parser) tracef(format string, args ...any) {
+    if p.tracer != nil {
+        p.tracer.Log(fmt.Sprintf(format, args...))
+    }
+}
+
+func traceToken(tok token.Token) string {
+    if tok.Type == token.EOF {
+        return "EOF"
+    }
+    if tok.Literal == string(tok.Type) {
+        return tok.Literal
+    }
+    return fmt.Sprintf("%s(%s)", tok.Type, tok.Literal)
+}
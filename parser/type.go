@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"monke/ast"
+	"monke/token"
+)
+
+var namedTypes = map[string]bool {
+    "int": true,
+    "float": true,
+    "bool": true,
+    "string": true,
+    "any": true,
+}
+
+// parseTypeAnnotation parses ': type' if it comes next, returning nil
+// without one. ok is false if there was an annotation but it is malformed.
+func (p *Parser) parseTypeAnnotation() (ast.Type, bool) {
+    if !p.isPeekToken(token.COLON) {
+        return nil, true
+    }
+    p.nextToken()
+    p.nextToken()
+
+    t := p.parseType()
+    return t, t != nil
+}
+
+// parseType parses a type starting on its first token:
+//
+//     int  float  bool  string  any
+//     array  array[int]  hash  hash[string, int]
+//     fn  fn(int, string) -> bool
+func (p *Parser) parseType() ast.Type {
+    defer p.untrace(p.trace("parseType"))
+
+    switch {
+    case p.isCurToken(token.FUNCTION):
+        return p.parseFunctionType()
+    case p.isCurToken(token.IDENT) && namedTypes[p.curToken.Literal]:
+        return &ast.NamedType{Token: p.curToken, Name: p.curToken.Literal}
+    case p.isCurToken(token.IDENT) && p.curToken.Literal == "array":
+        array := &ast.ArrayType{Token: p.curToken}
+        if p.isPeekToken(token.LBRACKET) {
+            p.nextToken()
+            p.nextToken()
+            array.Element = p.parseType()
+            if array.Element == nil || !p.expectPeek(token.RBRACKET, "']' after the element type") {
+                return nil
+            }
+        }
+        return array
+    case p.isCurToken(token.IDENT) && p.curToken.Literal == "hash":
+        hash := &ast.HashType{Token: p.curToken}
+        if p.isPeekToken(token.LBRACKET) {
+            p.nextToken()
+            p.nextToken()
+            hash.Key = p.parseType()
+            if hash.Key == nil || !p.expectPeek(token.COMMA, "',' after the key type") {
+                return nil
+            }
+            p.nextToken()
+            hash.Value = p.parseType()
+            if hash.Value == nil || !p.expectPeek(token.RBRACKET, "']' after the value type") {
+                return nil
+            }
+        }
+        return hash
+    case p.isCurToken(token.IDENT):
+        p.addError(
+            ErrUnknownType, p.curToken,
+            "unknown type '%s', expected int, float, bool, string, fn, array, hash or any", p.curToken.Literal)
+        return nil
+    }
+
+    p.addError(ErrUnexpectedToken, p.curToken, "expected a type, got %s", describe(p.curToken))
+    return nil
+}
+
+func (p *Parser) parseFunctionType() ast.Type {
+    fn := &ast.FunctionType{Token: p.curToken}
+    if !p.isPeekToken(token.LPAREN) {
+        return fn
+    }
+    p.nextToken()
+
+    fn.Parameters = []ast.Type{}
+    for !p.isPeekToken(token.RPAREN) {
+        p.nextToken()
+        param := p.parseType()
+        if param == nil {
+            return nil
+        }
+        fn.Parameters = append(fn.Parameters, param)
+
+        if !p.isPeekToken(token.COMMA) {
+            break
+        }
+        p.nextToken()
+    }
+    if !p.expectPeek(token.RPAREN, "',' or ')' in function type") {
+        return nil
+    }
+
+    if p.isPeekToken(token.ARROW) {
+        p.nextToken()
+        p.nextToken()
+        fn.Return = p.parseType()
+        if fn.Return == nil {
+            return nil
+        }
+    }
+    return fn
+}
@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"monke/ast"
+	"monke/lexer"
+	"testing"
+)
+
+func TestTypeAnnotations(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {"let x: int = 5", "let x: int = 5;"},
+        {"let xs: array[float] = []", "let xs: array[float] = [];"},
+        {"let h: hash[string, array] = {}", "let h: hash[string, array] = {};"},
+        {"let [a, b]: array[int] = xs", "let [a, b]: array[int] = xs;"},
+        {"let f: fn = g", "let f: fn = g;"},
+        {"let f: fn(int, bool) -> fn() -> any = g", "let f: fn(int, bool) -> fn() -> any = g;"},
+        {"let f = fn(a: int, b) -> string { a }", "let f = fn(a: int, b) -> string { a };"},
+        {"fn(x: hash) -> bool { true }", "fn(x: hash) -> bool { true }"},
+    }
+
+    for _, test := range tests {
+        p := New(lexer.New(test.input))
+        program := p.ParseProgram()
+        testParserErrors(t, p)
+
+        if program.String() != test.expected {
+            t.Errorf("%q - expected %q, got %q", test.input, test.expected, program.String())
+        }
+    }
+}
+
+func TestParameterTypes(t *testing.T) {
+    p := New(lexer.New("fn(a: int, b, c: array[string]) -> int { a }"))
+    program := p.ParseProgram()
+    testParserErrors(t, p)
+
+    fn := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+    if len(fn.ParameterTypes) != 3 {
+        t.Fatalf("expected 3 parameter types, got %d", len(fn.ParameterTypes))
+    }
+    if fn.ParameterTypes[0].String() != "int" || fn.ParameterTypes[1] != nil ||
+        fn.ParameterTypes[2].String() != "array[string]" {
+        t.Errorf("expected [int <nil> array[string]], got %v", fn.ParameterTypes)
+    }
+    if fn.ReturnType.String() != "int" {
+        t.Errorf("expected return type int, got %s", fn.ReturnType)
+    }
+}
+
+func TestTypeAnnotationErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        code ErrorCode
+        expected string
+    }{
+        {"let x: integer = 1", ErrUnknownType,
+            "1:8: unknown type 'integer', expected int, float, bool, string, fn, array, hash or any"},
+        {"let x: = 1", ErrUnexpectedToken, "1:8: expected a type, got '='"},
+        {"let x: array[int = 1", ErrUnexpectedToken, "1:18: expected ']' after the element type, got '='"},
+        {"let x: hash[int] = 1", ErrUnexpectedToken, "1:16: expected ',' after the key type, got ']'"},
+        {"fn(a: int b) {}", ErrUnexpectedToken, "1:11: expected next token ')', got 'IDENT'"},
+        {"fn() -> {}", ErrUnexpectedToken, "1:9: expected a type, got '{'"},
+    }
+
+    for _, test := range tests {
+        p := New(lexer.New(test.input))
+        p.ParseProgram()
+
+        errors := p.ErrorList()
+        if len(errors) == 0 {
+            t.Errorf("%q - expected error %q, got none", test.input, test.expected)
+            continue
+        }
+        if errors[0].Code != test.code || errors[0].Error() != test.expected {
+            t.Errorf("%q - expected %s error %q, got %s %q", test.input, test.code, test.expected, errors[0].Code, errors[0].Error())
+        }
+    }
+}
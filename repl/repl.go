@@ -4,8 +4,9 @@ import (
 	"bufio"
 	"fmt"
     "io"
-	"monke/token"
+	"monke/diagnostic"
 	"monke/lexer"
+	"monke/parser"
 )
 
 const PROMPT = ">> "
@@ -13,9 +14,10 @@ const PROMPT = ">> "
 
 func Start(in io.Reader, out io.Writer) {
     scanner := bufio.NewScanner(in)
+    renderer := diagnostic.Renderer{Color: diagnostic.IsTerminal(out)}
 
     for {
-        fmt.Printf(PROMPT)
+        fmt.Fprint(out, PROMPT)
         scanned := scanner.Scan()
         if !scanned {
             return
@@ -23,10 +25,14 @@ func Start(in io.Reader, out io.Writer) {
 
         line := scanner.Text()
         l := lexer.New(line)
+        p := parser.New(l)
 
-        for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
-            fmt.Printf("%+v\n", tok)
+        program := p.ParseProgram()
+        if errors := p.ErrorList(); len(errors) != 0 {
+            renderer.RenderErrors(out, line, errors)
+            continue
         }
 
+        fmt.Fprintln(out, program.String())
     }
 }
@@ -1,20 +1,59 @@
 package token
 
+import (
+    "fmt"
+    "unicode"
+)
+
 type TokenType string
 
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position // position of the first character
+	End     Position // position right after the last character
+}
+
+// Position is a location in the source text. Lines and columns start at 1,
+// the byte offset starts at 0. The zero Position is invalid.
+type Position struct {
+    Filename string
+    Offset   int
+    Line     int
+    Column   int
+}
+
+func (p Position) IsValid() bool {
+    return p.Line > 0
+}
+
+// String formats the position as file:line:col, leaving out
+// the parts that are not known.
+func (p Position) String() string {
+    s := p.Filename
+    if p.IsValid() {
+        if s != "" {
+            s += ":"
+        }
+        s += fmt.Sprintf("%d:%d", p.Line, p.Column)
+    }
+    if s == "" {
+        s = "-"
+    }
+    return s
 }
 
 const (
 	// Dont know about
 	ILLEGAL = "ILLEGAL"
 	EOF     = "EOF"
+	COMMENT = "COMMENT" // only emitted by lexers created with lexer.WithComments
 
 	// Identifiers + literals
 	IDENT = "IDENT" // add, foobar, x, y, ...
-	INT   = "INT"   // 1343456
+	INT   = "INT"   // 1343456, 0xff, 0o17, 0b1010, 1_000
+	FLOAT = "FLOAT" // 3.14, 1e-9
+	STRING = "STRING" // "foo bar"
 
 	// Operators
 	ASSIGN = "="
@@ -22,19 +61,36 @@ const (
     MINUS  = "-"
     ASTERISK = "*"
     SLASH    = "/"
+    PERCENT  = "%"
+    POWER    = "**"
     LT   = "<"
     GT   = ">"
+    LTE  = "<="
+    GTE  = ">="
     BANG      = "!"
     EQ = "=="
     NEQ = "!="
+    AND = "&&"
+    OR  = "||"
+
+    PLUS_ASSIGN     = "+="
+    MINUS_ASSIGN    = "-="
+    ASTERISK_ASSIGN = "*="
+    SLASH_ASSIGN    = "/="
+    FAT_ARROW       = "=>"
+    ELLIPSIS        = "..."
+    ARROW           = "->"
 
 	// Delimiters
 	COMMA     = ","
 	SEMICOLON = ";"
+	COLON     = ":"
 	LPAREN    = "("
 	RPAREN    = ")"
 	LBRACE    = "{"
 	RBRACE    = "}"
+	LBRACKET  = "["
+	RBRACKET  = "]"
 
 	// Keywords
 	FUNCTION = "FUNCTION"
@@ -44,6 +100,15 @@ const (
     RETURN   = "RETURN"
     TRUE     = "TRUE"
     FALSE    = "FALSE"
+    WHILE    = "WHILE"
+    FOR      = "FOR"
+    IN       = "IN"
+    BREAK    = "BREAK"
+    CONTINUE = "CONTINUE"
+    MATCH    = "MATCH"
+    IMPORT   = "IMPORT"
+    EXPORT   = "EXPORT"
+    AS       = "AS"
 )
 
 var keywords = map[string]TokenType{
@@ -54,6 +119,15 @@ var keywords = map[string]TokenType{
     "return": RETURN,
     "true": TRUE,
     "false": FALSE,
+    "while": WHILE,
+    "for": FOR,
+    "in": IN,
+    "break": BREAK,
+    "continue": CONTINUE,
+    "match": MATCH,
+    "import": IMPORT,
+    "export": EXPORT,
+    "as": AS,
 }
 
 func LookupIdent(ident string) TokenType {
@@ -64,7 +138,60 @@ func LookupIdent(ident string) TokenType {
     return IDENT
 }
 
+// symbols are the operators and delimiters the lexer knows by itself
+var symbols = map[string]TokenType{
+    "=": ASSIGN, "+": PLUS, "-": MINUS, "*": ASTERISK, "/": SLASH,
+    "%": PERCENT, "**": POWER, "<": LT, ">": GT, "<=": LTE, ">=": GTE,
+    "!": BANG, "==": EQ, "!=": NEQ, "&&": AND, "||": OR,
+    "+=": PLUS_ASSIGN, "-=": MINUS_ASSIGN, "*=": ASTERISK_ASSIGN, "/=": SLASH_ASSIGN,
+    "=>": FAT_ARROW, "...": ELLIPSIS, "->": ARROW,
+    ",": COMMA, ";": SEMICOLON, ":": COLON, "(": LPAREN, ")": RPAREN,
+    "{": LBRACE, "}": RBRACE, "[": LBRACKET, "]": RBRACKET,
+}
+
+// LookupSymbol returns the token type of a built-in operator or delimiter.
+func LookupSymbol(symbol string) (TokenType, bool) {
+    tok, ok := symbols[symbol]
+    return tok, ok
+}
+
+// Symbols returns the spellings of the built-in operators and delimiters,
+// in no particular order.
+func Symbols() []string {
+    spellings := []string{}
+    for symbol := range symbols {
+        spellings = append(spellings, symbol)
+    }
+    return spellings
+}
+
+// IsBuiltin reports whether t is one of the token types above, which
+// operators added to a lexer must not be confused with.
+func IsBuiltin(t TokenType) bool {
+    switch t {
+    case ILLEGAL, EOF, COMMENT, IDENT, INT, FLOAT, STRING:
+        return true
+    }
+    for _, keyword := range keywords {
+        if keyword == t {
+            return true
+        }
+    }
+    for _, symbol := range symbols {
+        if symbol == t {
+            return true
+        }
+    }
+    return false
+}
+
+// Identifiers start with a unicode letter or '_', followed by any number
+// of unicode letters, unicode decimal digits or '_'. So `_x1`, `größe`
+// and `变量` are identifiers, but `1x` is not.
+func IsIdentStart(ch rune) bool {
+    return ch == '_' || unicode.IsLetter(ch)
+}
 
-func IsIdentByte(ch byte) bool {
-    return (ch == '_') || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+func IsIdentPart(ch rune) bool {
+    return IsIdentStart(ch) || unicode.IsDigit(ch)
 }
@@ -0,0 +1,546 @@
+package types
+
+import (
+	"fmt"
+	"monke/ast"
+	"monke/token"
+)
+
+// Error is a value used where its type does not fit.
+type Error struct {
+    Pos, End token.Position // span of the first token of the offending expression
+    Msg string
+}
+
+func (e *Error) Error() string {
+    return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// scope holds the names bound by annotations or parts of annotated
+// values. Other names are bound to Any, so that they hide outer ones.
+type scope struct {
+    names map[string]Type
+    // names of annotated function literals bound without annotation,
+    // which become Any once assigned something else
+    inferred map[string]bool
+    outer *scope
+}
+
+func newScope(outer *scope) *scope {
+    return &scope{names: map[string]Type{}, inferred: map[string]bool{}, outer: outer}
+}
+
+// find returns the innermost scope name is bound in, or nil.
+func (s *scope) find(name string) *scope {
+    for ; s != nil; s = s.outer {
+        if _, ok := s.names[name]; ok {
+            return s
+        }
+    }
+    return nil
+}
+
+func (s *scope) lookup(name string) (Type, bool) {
+    if s := s.find(name); s != nil {
+        return s.names[name], true
+    }
+    return Any, false
+}
+
+type checker struct {
+    scope *scope
+    fn *Func // the innermost function literal, nil outside of any
+    types map[ast.Expression]Type // of every expression checked so far
+    errors []*Error
+}
+
+// Check reports where annotated names, parameters and return types are
+// given values of another type, and operators, calls and indexing that do
+// not fit the annotated type of a value. Names bound without annotation
+// are Any, unless bound to a function literal with annotations, and types
+// of literals alone are never reported, so unannotated code stays
+// dynamically typed.
+func Check(program *ast.Program) []*Error {
+    c := &checker{scope: newScope(nil), types: map[ast.Expression]Type{}}
+    c.statements(program.Statements)
+    return c.errors
+}
+
+func (c *checker) errorf(at ast.Expression, format string, args ...any) {
+    start := ast.StartOf(at)
+    c.errors = append(c.errors, &Error{Pos: start.Pos, End: start.End, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (c *checker) push() {
+    c.scope = newScope(c.scope)
+}
+
+func (c *checker) pop() {
+    c.scope = c.scope.outer
+}
+
+func (c *checker) declare(name *ast.Identifier, t Type) {
+    c.scope.names[name.Value] = t
+    delete(c.scope.inferred, name.Value)
+}
+
+// statements checks a list of statements and returns the type of the
+// last one if it is an expression, the value of the block it is in.
+func (c *checker) statements(statements []ast.Statement) Type {
+    var last Type = Any
+    for _, statement := range statements {
+        last = c.statement(statement)
+    }
+    return last
+}
+
+func (c *checker) block(block *ast.BlockStatement) Type {
+    c.push()
+    defer c.pop()
+    return c.statements(block.Statements)
+}
+
+func (c *checker) statement(statement ast.Statement) Type {
+    switch s := statement.(type) {
+    case *ast.ExpressionStatement:
+        return c.expression(s.Expression)
+    case *ast.LetStatement:
+        c.let(s)
+    case *ast.ExportStatement:
+        c.let(s.Statement)
+    case *ast.ImportStatement:
+        c.declare(s.Alias, Any)
+    case *ast.ReturnStatement:
+        c.returns(s)
+    case *ast.BlockStatement:
+        c.block(s)
+    case *ast.WhileStatement:
+        c.expression(s.Condition)
+        c.block(s.Body)
+    case *ast.ForStatement:
+        iterable := c.expression(s.Iterable)
+        if !c.annotated(s.Iterable) {
+            iterable = Any
+        }
+        c.push()
+        c.declare(s.Variable, elementOf(iterable))
+        c.statements(s.Body.Statements)
+        c.pop()
+    }
+    return Any
+}
+
+// let declares the names bound by a let statement, as Any without an
+// annotation. A function literal with annotations keeps its type, calls
+// to it are checked until the name is assigned something else.
+func (c *checker) let(let *ast.LetStatement) {
+    value := c.expression(let.Value)
+    declared := Type(Any)
+    if let.Type != nil {
+        declared = FromAST(let.Type)
+        if part, expected := c.misfit(declared, let.Value); part != nil {
+            c.errorf(part, "cannot use %s value as %s in let %s", c.types[part], expected, letTarget(let))
+        }
+    } else if _, ok := let.Value.(*ast.FunctionLiteral); ok && let.Pattern == nil && c.annotated(let.Value) {
+        c.declare(let.Name, value)
+        c.scope.inferred[let.Name.Value] = true
+        return
+    }
+    if let.Pattern != nil {
+        c.bindPattern(let.Pattern, declared)
+    } else {
+        c.declare(let.Name, declared)
+    }
+}
+
+func letTarget(let *ast.LetStatement) string {
+    if let.Pattern != nil {
+        return let.Pattern.String()
+    }
+    return let.Name.Value
+}
+
+// bindPattern declares the names in pattern with the types of the parts
+// of t they are bound to.
+func (c *checker) bindPattern(pattern ast.Pattern, t Type) {
+    switch pattern := pattern.(type) {
+    case *ast.BindingPattern:
+        c.declare(pattern.Name, t)
+    case *ast.ArrayPattern:
+        for _, element := range pattern.Elements {
+            c.bindPattern(element, elementOf(t))
+        }
+        if pattern.Rest != nil {
+            if _, ok := t.(*Array); ok {
+                c.declare(pattern.Rest, t)
+            } else {
+                c.declare(pattern.Rest, Any)
+            }
+        }
+    case *ast.HashPattern:
+        value := Type(Any)
+        if hash, ok := t.(*Hash); ok {
+            value = hash.Value
+        }
+        for _, pair := range pattern.Pairs {
+            c.bindPattern(pair.Value, value)
+        }
+    }
+}
+
+func elementOf(t Type) Type {
+    if array, ok := t.(*Array); ok {
+        return array.Elem
+    }
+    return Any
+}
+
+func (c *checker) returns(s *ast.ReturnStatement) {
+    if s.Value == nil {
+        return
+    }
+    c.expression(s.Value)
+    if c.fn != nil {
+        c.checkReturn(s.Value)
+    }
+}
+
+// checkReturn reports a value returned from the innermost function that
+// does not fit its return type.
+func (c *checker) checkReturn(value ast.Expression) {
+    if part, _ := c.misfit(c.fn.Return, value); part != nil {
+        c.errorf(part, "cannot return %s value from function returning %s", c.types[part], c.fn.Return)
+    }
+}
+
+// misfit returns the part of e that does not fit the expected type, and
+// the type it should have had, or nil if all of e fits. The elements of
+// array and hash literals are compared one by one, since mixed ones
+// would only make the literal an array or hash of any.
+func (c *checker) misfit(expected Type, e ast.Expression) (ast.Expression, Type) {
+    switch e := e.(type) {
+    case *ast.ArrayLiteral:
+        if array, ok := expected.(*Array); ok {
+            for _, element := range e.Elements {
+                if part, t := c.misfit(array.Elem, element); part != nil {
+                    return part, t
+                }
+            }
+            return nil, nil
+        }
+    case *ast.HashLiteral:
+        if hash, ok := expected.(*Hash); ok {
+            for _, pair := range e.Pairs {
+                if part, t := c.misfit(hash.Key, pair.Key); part != nil {
+                    return part, t
+                }
+                if part, t := c.misfit(hash.Value, pair.Value); part != nil {
+                    return part, t
+                }
+            }
+            return nil, nil
+        }
+    }
+    if !Assignable(expected, c.types[e]) {
+        return e, expected
+    }
+    return nil, nil
+}
+
+// expression returns the type of e and remembers it for misfit.
+func (c *checker) expression(e ast.Expression) Type {
+    t := c.typeOf(e)
+    c.types[e] = t
+    return t
+}
+
+func (c *checker) typeOf(e ast.Expression) Type {
+    switch e := e.(type) {
+    case *ast.Integer:
+        return Int
+    case *ast.Float:
+        return Float
+    case *ast.StringLiteral:
+        return String
+    case *ast.Boolean:
+        return Bool
+    case *ast.Identifier:
+        t, _ := c.scope.lookup(e.Value)
+        return t
+    case *ast.ArrayLiteral:
+        var elem Type
+        for _, element := range e.Elements {
+            elem = joinWith(elem, c.expression(element))
+        }
+        return &Array{Elem: orAny(elem)}
+    case *ast.HashLiteral:
+        var key, value Type
+        for _, pair := range e.Pairs {
+            key = joinWith(key, c.expression(pair.Key))
+            value = joinWith(value, c.expression(pair.Value))
+        }
+        return &Hash{Key: orAny(key), Value: orAny(value)}
+    case *ast.PrefixExpression:
+        return c.prefix(e)
+    case *ast.InfixExpression:
+        left, right := c.expression(e.Left), c.expression(e.Right)
+        return c.operator(e, e.Operator, left, right, c.annotated(e.Left) || c.annotated(e.Right))
+    case *ast.AssignExpression:
+        return c.assign(e)
+    case *ast.IndexExpression:
+        return c.index(e)
+    case *ast.CallExpression:
+        return c.call(e)
+    case *ast.FunctionLiteral:
+        return c.function(e)
+    case *ast.IfExpression:
+        c.expression(e.Condition)
+        consequence := c.block(e.Consequence)
+        switch alternative := e.Alternative.(type) {
+        case *ast.BlockStatement:
+            return join(consequence, c.block(alternative))
+        case *ast.IfExpression:
+            return join(consequence, c.expression(alternative))
+        }
+        return Any
+    case *ast.MatchExpression:
+        c.expression(e.Subject)
+        for _, arm := range e.Arms {
+            c.push()
+            c.bindPattern(arm.Pattern, Any)
+            if arm.Guard != nil {
+                c.expression(arm.Guard)
+            }
+            c.expression(arm.Body)
+            c.pop()
+        }
+    }
+    return Any
+}
+
+func joinWith(acc, t Type) Type {
+    if acc == nil {
+        return t
+    }
+    return join(acc, t)
+}
+
+func orAny(t Type) Type {
+    if t == nil {
+        return Any
+    }
+    return t
+}
+
+func (c *checker) prefix(e *ast.PrefixExpression) Type {
+    right := c.expression(e.Right)
+    switch e.Operator {
+    case "-":
+        if right != Any && !isNumeric(right) && c.annotated(e.Right) {
+            c.errorf(e, "operator - is not defined on %s", right)
+            return Any
+        }
+        return right
+    case "!":
+        return Bool
+    }
+    return Any
+}
+
+// operator returns the type of left op right, reporting operands it is
+// not defined on when both of their types are known and one of them comes
+// from an annotation.
+func (c *checker) operator(at ast.Expression, op string, left, right Type, annotated bool) Type {
+    switch op {
+    case "==", "!=":
+        return Bool
+    case "&&", "||":
+        return Any
+    }
+    if left == Any || right == Any {
+        if op == "<" || op == ">" || op == "<=" || op == ">=" {
+            return Bool
+        }
+        return Any
+    }
+
+    switch op {
+    case "+":
+        if left == String && right == String {
+            return String
+        }
+        fallthrough
+    case "-", "*", "/", "%", "**":
+        if isNumeric(left) && isNumeric(right) {
+            return join(left, right)
+        }
+    case "<", ">", "<=", ">=":
+        if isNumeric(left) && isNumeric(right) || left == String && right == String {
+            return Bool
+        }
+    default:
+        // operators added with parser.WithGrammar mean whatever they are
+        // made to mean
+        return Any
+    }
+    if annotated {
+        c.errorf(at, "operator %s is not defined on %s and %s", op, left, right)
+    }
+    return Any
+}
+
+func (c *checker) assign(e *ast.AssignExpression) Type {
+    target := c.expression(e.Target)
+    value := c.expression(e.Value)
+    if e.Operator != "=" {
+        // x += v is x = x + v
+        op := e.Operator[:len(e.Operator) - 1]
+        value = c.operator(e, op, target, value, c.annotated(e.Target) || c.annotated(e.Value))
+        if !Assignable(target, value) {
+            c.errorf(e.Value, "cannot assign %s value to %s of type %s", value, e.Target, target)
+        }
+        return value
+    }
+    if name, ok := e.Target.(*ast.Identifier); ok {
+        if s := c.scope.find(name.Value); s != nil && s.inferred[name.Value] {
+            s.names[name.Value] = Any
+            delete(s.inferred, name.Value)
+            return value
+        }
+    }
+    if part, expected := c.misfit(target, e.Value); part != nil {
+        c.errorf(part, "cannot assign %s value to %s of type %s", c.types[part], e.Target, expected)
+    }
+    return value
+}
+
+func (c *checker) index(e *ast.IndexExpression) Type {
+    left := c.expression(e.Left)
+    index := c.expression(e.Index)
+
+    var key, elem Type
+    switch left := left.(type) {
+    case *Array:
+        key, elem = Int, left.Elem
+    case *Hash:
+        key, elem = left.Key, left.Value
+    default:
+        if left == String {
+            key, elem = Int, String
+        } else if left != Any && c.annotated(e.Left) {
+            c.errorf(e.Left, "cannot index %s value", left)
+            return Any
+        } else {
+            return Any
+        }
+    }
+
+    if !Assignable(key, index) && (c.annotated(e.Left) || c.annotated(e.Index)) {
+        c.errorf(e.Index, "cannot index %s with %s value", left, index)
+    }
+    return elem
+}
+
+func (c *checker) call(e *ast.CallExpression) Type {
+    function := c.expression(e.Function)
+    args := []Type{}
+    for _, arg := range e.Arguments {
+        args = append(args, c.expression(arg))
+    }
+
+    // fn(a, b) { ... }(1) is left to fail at run time
+    if !c.annotated(e.Function) {
+        return Any
+    }
+    f, ok := function.(*Func)
+    if !ok {
+        if function != Any {
+            c.errorf(e.Function, "cannot call %s value", function)
+        }
+        return Any
+    }
+    if f.Params == nil {
+        return f.Return
+    }
+
+    if len(args) != len(f.Params) {
+        c.errorf(e.Function, "%s takes %d arguments, got %d", e.Function, len(f.Params), len(args))
+        return f.Return
+    }
+    for i, arg := range e.Arguments {
+        if part, expected := c.misfit(f.Params[i], arg); part != nil {
+            c.errorf(part, "cannot use %s value as %s argument %d to %s", c.types[part], expected, i + 1, e.Function)
+        }
+    }
+    return f.Return
+}
+
+// function checks the body of a function literal and returns its type.
+// The value of the last statement is returned like an explicit return.
+func (c *checker) function(e *ast.FunctionLiteral) Type {
+    f := &Func{Params: []Type{}, Return: FromAST(e.ReturnType)}
+
+    c.push()
+    defer c.pop()
+    for i, param := range e.Parameters {
+        var annotation ast.Type
+        if i < len(e.ParameterTypes) {
+            annotation = e.ParameterTypes[i]
+        }
+        f.Params = append(f.Params, FromAST(annotation))
+        c.declare(param, f.Params[i])
+    }
+
+    outer := c.fn
+    c.fn = f
+    defer func() { c.fn = outer }()
+
+    c.statements(e.Body.Statements)
+    if n := len(e.Body.Statements); n != 0 {
+        if s, ok := e.Body.Statements[n - 1].(*ast.ExpressionStatement); ok {
+            c.checkReturn(s.Expression)
+        }
+    }
+    return f
+}
+
+// annotated reports whether the type of e comes from an annotation rather
+// than only from literals, like the int of 1 + 2, which unannotated code
+// is free to misuse until it runs.
+func (c *checker) annotated(e ast.Expression) bool {
+    switch e := e.(type) {
+    case *ast.Identifier:
+        t, ok := c.scope.lookup(e.Value)
+        return ok && t != Any
+    case *ast.FunctionLiteral:
+        for _, t := range e.ParameterTypes {
+            if t != nil {
+                return true
+            }
+        }
+        return e.ReturnType != nil
+    case *ast.CallExpression:
+        return c.annotated(e.Function)
+    case *ast.IndexExpression:
+        return c.annotated(e.Left)
+    case *ast.PrefixExpression:
+        return c.annotated(e.Right)
+    case *ast.InfixExpression:
+        return c.annotated(e.Left) || c.annotated(e.Right)
+    case *ast.AssignExpression:
+        return c.annotated(e.Value)
+    case *ast.ArrayLiteral:
+        for _, element := range e.Elements {
+            if c.annotated(element) {
+                return true
+            }
+        }
+    case *ast.HashLiteral:
+        for _, pair := range e.Pairs {
+            if c.annotated(pair.Key) || c.annotated(pair.Value) {
+                return true
+            }
+        }
+    }
+    return false
+}
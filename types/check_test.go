@@ -0,0 +1,124 @@
+package types
+
+import (
+	"monke/lexer"
+	"monke/parser"
+	"testing"
+)
+
+func check(t *testing.T, input string) []*Error {
+    t.Helper()
+    p := parser.New(lexer.New(input))
+    program := p.ParseProgram()
+    if errors := p.Errors(); len(errors) != 0 {
+        t.Fatalf("%q - parser errors: %v", input, errors)
+    }
+    return Check(program)
+}
+
+func TestCheckAccepts(t *testing.T) {
+    tests := []string{
+        // unannotated code stays dynamic
+        `let x = 5; x = "five"; x(1) + true`,
+        `let f = fn(a, b) { a + b }; f(1, "2")`,
+        "let add = fn(x, y) { x + y }; add(1);",
+        `1 + "a"; -"a"; [1, 2]["a"]; 5(1); fn(x) { x }(1, 2)`,
+        `for (x in [1, 2]) { x + "a" }`,
+        // functions bound without annotation can be replaced by anything
+        "let f = fn(x) { x }; let g = fn() { f(1, 2) }; f = fn(a, b) { a };",
+        "let f = fn(a: int) { a }; f = 1; f(true)",
+        "let f = fn(a: int) { a }; if (true) { f = fn(s) { s }; f(true) }",
+        // ints can be used as floats
+        "let x: float = 1; x = 2",
+        "let f = fn(a: int, b: float) -> float { a * b }",
+        `let s: string = "a" + "b"; let b: bool = s < "c"`,
+        "let xs: array[int] = [1, 2, 3]; let n: int = xs[0]",
+        `let h: hash[string, int] = {"a": 1}; let n: int = h["a"]`,
+        "let xs: array = [1, true]; let h: hash = {}",
+        "let f: fn(int) -> int = fn(n: int) -> int { n }; let n: int = f(1)",
+        "let f: fn = fn(a, b) { a }; f(1, 2, 3)",
+        "let apply = fn(f: fn(int) -> int, x: int) -> int { f(x) }; apply(fn(n) { n }, 1)",
+        "let [a, b]: array[int] = [1, 2]; let c: int = a + b",
+        "let n: int = if (true) { 1 } else { 2 }",
+        "let f = fn(n: int) -> int { if (n < 2) { return 1; } n * f(n - 1) }",
+        "let xs: array[string] = []; for (x in xs) { let s: string = x }",
+        "let f = fn() -> int { while (true) { return 1; } }",
+    }
+
+    for _, input := range tests {
+        if errors := check(t, input); len(errors) != 0 {
+            t.Errorf("%q - expected no errors, got %v", input, errors)
+        }
+    }
+}
+
+func TestCheckErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {`let x: int = "five"`, "1:14: cannot use string value as int in let x"},
+        {"let x: int = 1.5", "1:14: cannot use float value as int in let x"},
+        {`let xs: array[int] = ["a"]`, "1:23: cannot use string value as int in let xs"},
+        {`let xs: array[int] = [1, "a"]`, "1:26: cannot use string value as int in let xs"},
+        {`let h: hash[string, int] = {"a": 1, "b": "x"}`, "1:42: cannot use string value as int in let h"},
+        {`let h: hash[string, int] = {1: 1}`, "1:29: cannot use int value as string in let h"},
+        {"let xs: array[array[int]] = [[1], [true]]", "1:36: cannot use bool value as int in let xs"},
+        {`let f = fn(xs: array[int]) { xs }; f([1, "a"])`, "1:42: cannot use string value as int argument 1 to f"},
+        {`let f = fn() -> array[int] { [1, "a"] }`, "1:34: cannot return string value from function returning array[int]"},
+        {"let [a, b]: hash = [1, 2]", "1:20: cannot use array[int] value as hash in let [a, b]"},
+        {"let x: int = 1; x = true", "1:21: cannot assign bool value to x of type int"},
+        {`let s: string = ""; s += 1`, "1:21: operator + is not defined on string and int"},
+        {"let f = fn(a: int) { a }; f(true)", "1:29: cannot use bool value as int argument 1 to f"},
+        {"let f = fn(a: int, b) { a }; f(1)", "1:30: f takes 2 arguments, got 1"},
+        {"let add = fn(a: int, b: int) -> int { a + b }; add(1, add)",
+            "1:55: cannot use fn(int, int) -> int value as int argument 2 to add"},
+        {`let scale = fn(xs: array[float], by: float) -> array[float] { xs }; scale([1.5], "x")`,
+            "1:82: cannot use string value as float argument 2 to scale"},
+        {"let f: fn(int) = fn(a) { a }; f(true)", "1:33: cannot use bool value as int argument 1 to f"},
+        {`fn(a: int) { a }("a")`, "1:18: cannot use string value as int argument 1 to fn(a: int) { a }"},
+        {"let x: int = 1; x(2)", "1:17: cannot call int value"},
+        {"let f = fn() -> string { return 1; }", "1:33: cannot return int value from function returning string"},
+        {"let f = fn(b: bool) -> int { b }", "1:30: cannot return bool value from function returning int"},
+        {"let f = fn(s: string) { s - 1 }", "1:25: operator - is not defined on string and int"},
+        {"let f = fn(b: bool) { -b }", "1:23: operator - is not defined on bool"},
+        {"let x: int = 1; x[0]", "1:17: cannot index int value"},
+        {`let xs: array = []; xs["a"]`, "1:24: cannot index array with string value"},
+        {"let f: fn(int) -> int = fn(s: string) -> int { 1 }",
+            "1:25: cannot use fn(string) -> int value as fn(int) -> int in let f"},
+        {"let [a]: array[bool] = [true]; let n: int = a", "1:45: cannot use bool value as int in let n"},
+    }
+
+    for _, test := range tests {
+        errors := check(t, test.input)
+        if len(errors) != 1 || errors[0].Error() != test.expected {
+            t.Errorf("%q - expected error %q, got %v", test.input, test.expected, errors)
+        }
+    }
+}
+
+func TestAssignable(t *testing.T) {
+    tests := []struct {
+        to, from Type
+        expected bool
+    }{
+        {Int, Int, true},
+        {Float, Int, true},
+        {Int, Float, false},
+        {Any, String, true},
+        {String, Any, true},
+        {&Array{Elem: Float}, &Array{Elem: Int}, true},
+        {&Array{Elem: Int}, &Hash{Key: Int, Value: Int}, false},
+        {&Func{Params: nil, Return: Any}, &Func{Params: []Type{Int}, Return: Bool}, true},
+        {&Func{Params: []Type{Int}, Return: Any}, &Func{Params: []Type{Int, Int}, Return: Any}, false},
+        // a function taking floats can be called with ints, not the other way
+        {&Func{Params: []Type{Int}, Return: Any}, &Func{Params: []Type{Float}, Return: Any}, true},
+        {&Func{Params: []Type{Float}, Return: Any}, &Func{Params: []Type{Int}, Return: Any}, false},
+    }
+
+    for _, test := range tests {
+        if Assignable(test.to, test.from) != test.expected {
+            t.Errorf("Assignable(%s, %s) - expected %t", test.to, test.from, test.expected)
+        }
+    }
+}
@@ -0,0 +1,147 @@
+package types
+
+import (
+	"monke/ast"
+	"strings"
+)
+
+// Type is the static type of a value. Any stands for values whose type
+// is not known until they are run, which is everything not annotated.
+type Type interface {
+    String() string
+}
+
+// Basic is one of the types that have no parts.
+type Basic string
+
+const (
+    Any    Basic = "any"
+    Int    Basic = "int"
+    Float  Basic = "float"
+    Bool   Basic = "bool"
+    String Basic = "string"
+)
+
+func (b Basic) String() string { return string(b) }
+
+type Array struct {
+    Elem Type
+}
+
+func (a *Array) String() string {
+    if a.Elem == Any {
+        return "array"
+    }
+    return "array[" + a.Elem.String() + "]"
+}
+
+type Hash struct {
+    Key, Value Type
+}
+
+func (h *Hash) String() string {
+    if h.Key == Any && h.Value == Any {
+        return "hash"
+    }
+    return "hash[" + h.Key.String() + ", " + h.Value.String() + "]"
+}
+
+// Func is the type of functions. Params is nil when any arguments are
+// accepted, like for the plain fn annotation.
+type Func struct {
+    Params []Type
+    Return Type
+}
+
+func (f *Func) String() string {
+    if f.Params == nil && f.Return == Any {
+        return "fn"
+    }
+    params := []string{}
+    for _, p := range f.Params {
+        params = append(params, p.String())
+    }
+    out := "fn(" + strings.Join(params, ", ") + ")"
+    if f.Return != Any {
+        out += " -> " + f.Return.String()
+    }
+    return out
+}
+
+// FromAST returns the type an annotation stands for, Any for nil.
+func FromAST(t ast.Type) Type {
+    switch t := t.(type) {
+    case *ast.NamedType:
+        return Basic(t.Name)
+    case *ast.ArrayType:
+        return &Array{Elem: FromAST(t.Element)}
+    case *ast.HashType:
+        return &Hash{Key: FromAST(t.Key), Value: FromAST(t.Value)}
+    case *ast.FunctionType:
+        f := &Func{Return: FromAST(t.Return)}
+        if t.Parameters != nil {
+            f.Params = []Type{}
+            for _, p := range t.Parameters {
+                f.Params = append(f.Params, FromAST(p))
+            }
+        }
+        return f
+    }
+    return Any
+}
+
+// Assignable reports whether a value of type from can be used where to
+// is expected. Any goes both ways, and ints can be used as floats.
+func Assignable(to, from Type) bool {
+    if to == Any || from == Any || to == from {
+        return true
+    }
+
+    switch to := to.(type) {
+    case Basic:
+        return to == Float && from == Int
+    case *Array:
+        from, ok := from.(*Array)
+        return ok && Assignable(to.Elem, from.Elem)
+    case *Hash:
+        from, ok := from.(*Hash)
+        return ok && Assignable(to.Key, from.Key) && Assignable(to.Value, from.Value)
+    case *Func:
+        from, ok := from.(*Func)
+        if !ok || !Assignable(to.Return, from.Return) {
+            return false
+        }
+        if to.Params == nil || from.Params == nil {
+            return true
+        }
+        if len(to.Params) != len(from.Params) {
+            return false
+        }
+        // the function gets called with the arguments to accepts
+        for i := range to.Params {
+            if !Assignable(from.Params[i], to.Params[i]) {
+                return false
+            }
+        }
+        return true
+    }
+    return false
+}
+
+func isNumeric(t Type) bool {
+    return t == Int || t == Float
+}
+
+// join returns the type both a and b can be used as, Any if there is
+// none more specific.
+func join(a, b Type) Type {
+    switch {
+    case a == b:
+        return a
+    case isNumeric(a) && isNumeric(b):
+        return Float
+    case a.String() == b.String():
+        return a
+    }
+    return Any
+}